@@ -0,0 +1,63 @@
+// Command fakeyahoo serves a scriptable fake of the Yahoo Finance API.
+// Run the bot with YFA_BASE_URL=http://<addr> to use it instead of Yahoo.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+
+	"github.com/stollenaar/stockbot/internal/util/yfa/fakeyahoo"
+)
+
+var (
+	Addr         = flag.String("addr", "127.0.0.1:8089", "Address to serve the fake on")
+	ScenarioPath = flag.String("scenario", "", "Scenario JSON file with the symbols and scripted steps, the default symbols are used when empty")
+)
+
+// defaultScenario is served when no scenario file is given
+var defaultScenario = fakeyahoo.Scenario{
+	Symbols: []fakeyahoo.ScenarioSymbol{
+		{Symbol: "AAPL", Name: "Apple Inc.", Price: 230},
+		{Symbol: "MSFT", Name: "Microsoft Corporation", Price: 420},
+		{Symbol: "SPY", Name: "SPDR S&P 500 ETF Trust", Price: 560},
+	},
+}
+
+func main() {
+	flag.Parse()
+
+	scenario := defaultScenario
+	if *ScenarioPath != "" {
+		var err error
+		if scenario, err = fakeyahoo.LoadScenario(*ScenarioPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	fake := fakeyahoo.New()
+	fake.Seed(scenario)
+	go func() {
+		if err := fake.Play(ctx, scenario); err != nil && ctx.Err() == nil {
+			slog.Error("Scenario stopped", slog.Any("err", err))
+		}
+	}()
+
+	server := &http.Server{Addr: *Addr, Handler: fake}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	slog.Info("Serving fake Yahoo Finance", slog.String("addr", *Addr), slog.Int("symbols", len(scenario.Symbols)), slog.Int("steps", len(scenario.Steps)))
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
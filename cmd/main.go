@@ -19,12 +19,18 @@ import (
 	"github.com/disgoorg/disgo/gateway"
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/stollenaar/stockbot/internal/commands"
+	"github.com/stollenaar/stockbot/internal/database"
 	"github.com/stollenaar/stockbot/internal/util"
 	"github.com/stollenaar/stockbot/internal/util/trackers"
+	"github.com/stollenaar/stockbot/internal/util/yfa"
 )
 
 var (
-	client *bot.Client
+	client   *bot.Client
+	provider yfa.MarketDataProvider
+
+	// ctx is cancelled on shutdown, which aborts in-flight interactions and background checks
+	ctx, cancel = context.WithCancel(context.Background())
 
 	GuildID        = flag.String("guild", "", "Test guild ID. If not passed - bot registers commands globally")
 	Debug          = flag.Bool("debug", false, "Run in debug mode")
@@ -38,11 +44,25 @@ func init() {
 	c, err := disgo.New(util.GetDiscordToken(),
 		bot.WithGatewayConfigOpts(gateway.WithIntents(gateway.IntentDirectMessages)),
 		bot.WithEventListenerFunc(func(event *events.ApplicationCommandInteractionCreate) {
+			interactionCtx, interactionCancel := context.WithTimeout(ctx, commands.InteractionTimeout)
+			defer interactionCancel()
+
 			data := event.SlashCommandInteractionData()
-			commands.CommandHandlers[data.CommandName()](event)
+			commands.CommandHandlers[data.CommandName()](interactionCtx, event)
 		}),
 		bot.WithEventListenerFunc(func(event *events.ComponentInteractionCreate) {
-			commands.ComponentHandlers[strings.Split(event.Data.CustomID(), ";")[0]](event)
+			interactionCtx, interactionCancel := context.WithTimeout(ctx, commands.InteractionTimeout)
+			defer interactionCancel()
+
+			commands.ComponentHandlers[strings.Split(event.Data.CustomID(), ";")[0]](interactionCtx, event)
+		}),
+		bot.WithEventListenerFunc(func(event *events.AutocompleteInteractionCreate) {
+			autocompleteCtx, autocompleteCancel := context.WithTimeout(ctx, commands.AutocompleteTimeout)
+			defer autocompleteCancel()
+
+			if handler, ok := commands.AutocompleteHandlers[event.Data.CommandName]; ok {
+				handler(autocompleteCtx, event)
+			}
 		}),
 		// bot.WithEventListenerFunc(func(event *events.ModalSubmitInteractionCreate) {
 		// 	commands.ModalSubmitHandlers[event.Data.CustomID](event)
@@ -57,6 +77,39 @@ func init() {
 	}
 	client = c
 	util.ConfigFile.DEBUG = *Debug
+
+	if util.ConfigFile.YFA_BASE_URL != "" {
+		yfa.SetBaseURL(util.ConfigFile.YFA_BASE_URL)
+		slog.Info("Using Yahoo Finance base URL", slog.String("url", util.ConfigFile.YFA_BASE_URL))
+	}
+
+	if util.ConfigFile.YFA_CASSETTE != "" {
+		recorder, err := yfa.NewRecorder(util.ConfigFile.YFA_CASSETTE, yfa.CassetteMode(util.ConfigFile.YFA_CASSETTE_MODE), nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		yfa.ConfigureSharedClient(yfa.WithTransport(recorder))
+		slog.Info("Using Yahoo Finance cassette", slog.String("path", util.ConfigFile.YFA_CASSETTE), slog.String("mode", util.ConfigFile.YFA_CASSETTE_MODE))
+	}
+
+	var providers []yfa.NamedProvider
+	for _, name := range util.ConfigFile.DATA_PROVIDERS {
+		switch name {
+		case "yahoo":
+			providers = append(providers, yfa.NamedProvider{Name: name, Provider: yfa.NewYahooProvider()})
+		case "stooq":
+			providers = append(providers, yfa.NamedProvider{Name: name, Provider: yfa.NewStooqProvider(util.ConfigFile.STOOQ_URL)})
+		default:
+			log.Fatalf("unknown data provider: %s", name)
+		}
+	}
+	provider = yfa.NewCachedProvider(yfa.NewFailoverProvider(providers...), yfa.DefaultCacheTTL)
+	commands.SetProvider(provider)
+	database.SetProvider(provider)
+
+	if err := database.Open(util.ConfigFile.DUCKDB_PATH); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func main() {
@@ -119,11 +172,12 @@ func main() {
 		log.Fatal("error while connecting to gateway: ", err)
 	}
 	slog.Info("Bot started")
-	trackers.StartChecker(client)
+	trackers.StartChecker(ctx, client, provider)
 
 	sc := make(chan os.Signal, 1)
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
+	cancel()
 
 	if *RemoveCommands {
 		log.Println("Removing commands...")
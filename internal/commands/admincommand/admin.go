@@ -0,0 +1,116 @@
+package admincommand
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/disgo/events"
+	"github.com/stollenaar/stockbot/internal/util"
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+)
+
+var (
+	AdminCmd = AdminCommand{
+		Name:        "admin",
+		Description: "Admin interaction command",
+	}
+
+	provider yfa.MarketDataProvider = yfa.NewYahooProvider()
+)
+
+type AdminCommand struct {
+	Name        string
+	Description string
+}
+
+// cacheStatser is implemented by providers that keep cache metrics, like yfa.CachedProvider
+type cacheStatser interface {
+	CacheStats() map[string]yfa.CacheStats
+}
+
+// SetProvider overrides the market data provider whose metrics are reported
+func SetProvider(p yfa.MarketDataProvider) {
+	provider = p
+}
+
+func (s AdminCommand) Handler(ctx context.Context, event *events.ApplicationCommandInteractionCreate) {
+	err := event.DeferCreateMessage(true)
+
+	if err != nil {
+		slog.Error("Error deferring: ", slog.Any("err", err))
+		return
+	}
+
+	if event.User().ID.String() != util.ConfigFile.ADMIN_USER_ID {
+		response := "You are not allowed to use this command"
+		_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content: &response,
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+		return
+	}
+
+	sub := event.SlashCommandInteractionData()
+
+	switch *sub.SubCommandName {
+	case "stats":
+		statsHandler(event)
+	}
+}
+
+func (s AdminCommand) CreateCommandArguments() []discord.ApplicationCommandOption {
+	return []discord.ApplicationCommandOption{
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "stats",
+			Description: "show market data cache and rate limiter statistics",
+		},
+	}
+}
+
+func statsHandler(event *events.ApplicationCommandInteractionCreate) {
+	var embed discord.Embed
+
+	embed.Title = "Market Data Statistics"
+
+	if cached, ok := provider.(cacheStatser); ok {
+		stats := cached.CacheStats()
+		for _, endpoint := range slices.Sorted(maps.Keys(stats)) {
+			hits, misses := stats[endpoint].Hits, stats[endpoint].Misses
+			ratio := 0.0
+			if hits+misses > 0 {
+				ratio = float64(hits) / float64(hits+misses) * 100
+			}
+			embed.Fields = append(embed.Fields, discord.EmbedField{
+				Name:   fmt.Sprintf("Cache: %s", endpoint),
+				Value:  fmt.Sprintf("Hits: %d\nMisses: %d\nHit ratio: %.1f%%", hits, misses, ratio),
+				Inline: util.Pointer(true),
+			})
+		}
+	} else {
+		embed.Description = "The market data provider is not cached"
+	}
+
+	limits := yfa.SharedRateLimitStats()
+	for _, endpoint := range slices.Sorted(maps.Keys(limits)) {
+		stats := limits[endpoint]
+		embed.Fields = append(embed.Fields, discord.EmbedField{
+			Name:   fmt.Sprintf("Requests: %s", endpoint),
+			Value:  fmt.Sprintf("Sent: %d\nDelayed: %d\nCancelled: %d\nThrottled: %d\nWaited: %s", stats.Requests, stats.Delayed, stats.Cancelled, stats.Throttled, stats.WaitTime.Round(time.Millisecond)),
+			Inline: util.Pointer(true),
+		})
+	}
+
+	_, err := event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Embeds: &[]discord.Embed{embed},
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err), slog.Any(". With body:", embed))
+	}
+}
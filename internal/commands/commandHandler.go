@@ -1,27 +1,38 @@
 package commands
 
 import (
+	"context"
 	"reflect"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
+	"github.com/stollenaar/stockbot/internal/commands/admincommand"
 	"github.com/stollenaar/stockbot/internal/commands/portfoliocommand"
 	"github.com/stollenaar/stockbot/internal/commands/stockcommand"
 	"github.com/stollenaar/stockbot/internal/commands/watchcommand"
 	"github.com/stollenaar/stockbot/internal/util"
+	"github.com/stollenaar/stockbot/internal/util/yfa"
 )
 
+// InteractionTimeout bounds the handling of a single interaction, Discord invalidates the interaction token after 15 minutes
+const InteractionTimeout = 14 * time.Minute
+
+// AutocompleteTimeout bounds an autocomplete lookup, Discord drops the suggestions after 3 seconds
+const AutocompleteTimeout = 2500 * time.Millisecond
+
 type CommandI interface {
-	Handler(e *events.ApplicationCommandInteractionCreate)
+	Handler(ctx context.Context, e *events.ApplicationCommandInteractionCreate)
 	CreateCommandArguments() []discord.ApplicationCommandOption
 }
 
 var (
-	Commands            = []CommandI{stockcommand.StockCmd, watchcommand.WatchCmd, portfoliocommand.PortfolioCmd}
-	ApplicationCommands []discord.ApplicationCommandCreate
-	CommandHandlers     = make(map[string]func(e *events.ApplicationCommandInteractionCreate))
-	ModalSubmitHandlers = make(map[string]func(e *events.ModalSubmitInteractionCreate))
-	ComponentHandlers   = make(map[string]func(e *events.ComponentInteractionCreate))
+	Commands             = []CommandI{stockcommand.StockCmd, watchcommand.WatchCmd, portfoliocommand.PortfolioCmd, admincommand.AdminCmd}
+	ApplicationCommands  []discord.ApplicationCommandCreate
+	CommandHandlers      = make(map[string]func(ctx context.Context, e *events.ApplicationCommandInteractionCreate))
+	ModalSubmitHandlers  = make(map[string]func(ctx context.Context, e *events.ModalSubmitInteractionCreate))
+	ComponentHandlers    = make(map[string]func(ctx context.Context, e *events.ComponentInteractionCreate))
+	AutocompleteHandlers = make(map[string]func(ctx context.Context, e *events.AutocompleteInteractionCreate))
 )
 
 func init() {
@@ -34,15 +45,25 @@ func init() {
 		CommandHandlers[reflect.ValueOf(cmd).FieldByName("Name").String()] = cmd.Handler
 
 		if _, ok := reflect.TypeOf(cmd).MethodByName("ModalHandler"); ok {
-			ModalSubmitHandlers[reflect.ValueOf(cmd).FieldByName("Name").String()] = func(e *events.ModalSubmitInteractionCreate) {
+			ModalSubmitHandlers[reflect.ValueOf(cmd).FieldByName("Name").String()] = func(ctx context.Context, e *events.ModalSubmitInteractionCreate) {
 				reflect.ValueOf(cmd).MethodByName("ModalHandler").Call([]reflect.Value{
+					reflect.ValueOf(ctx),
 					reflect.ValueOf(e),
 				})
 			}
 		}
 		if _, ok := reflect.TypeOf(cmd).MethodByName("ComponentHandler"); ok {
-			ComponentHandlers[reflect.ValueOf(cmd).FieldByName("Name").String()] = func(e *events.ComponentInteractionCreate) {
+			ComponentHandlers[reflect.ValueOf(cmd).FieldByName("Name").String()] = func(ctx context.Context, e *events.ComponentInteractionCreate) {
 				reflect.ValueOf(cmd).MethodByName("ComponentHandler").Call([]reflect.Value{
+					reflect.ValueOf(ctx),
+					reflect.ValueOf(e),
+				})
+			}
+		}
+		if _, ok := reflect.TypeOf(cmd).MethodByName("AutocompleteHandler"); ok {
+			AutocompleteHandlers[reflect.ValueOf(cmd).FieldByName("Name").String()] = func(ctx context.Context, e *events.AutocompleteInteractionCreate) {
+				reflect.ValueOf(cmd).MethodByName("AutocompleteHandler").Call([]reflect.Value{
+					reflect.ValueOf(ctx),
 					reflect.ValueOf(e),
 				})
 			}
@@ -59,8 +80,16 @@ func init() {
 	CommandHandlers["ping"] = PingCommand
 }
 
+// SetProvider injects the market data provider into every command that needs one
+func SetProvider(provider yfa.MarketDataProvider) {
+	stockcommand.SetProvider(provider)
+	portfoliocommand.SetProvider(provider)
+	watchcommand.SetProvider(provider)
+	admincommand.SetProvider(provider)
+}
+
 // PingCommand sends back the pong
-func PingCommand(event *events.ApplicationCommandInteractionCreate) {
+func PingCommand(ctx context.Context, event *events.ApplicationCommandInteractionCreate) {
 	event.CreateMessage(discord.MessageCreate{
 		Content: "Pong",
 		Flags:   util.ConfigFile.SetEphemeral(),
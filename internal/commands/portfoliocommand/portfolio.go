@@ -1,8 +1,13 @@
 package portfoliocommand
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -12,6 +17,7 @@ import (
 	"github.com/disgoorg/snowflake/v2"
 	"github.com/stollenaar/stockbot/internal/database"
 	"github.com/stollenaar/stockbot/internal/util"
+	"github.com/stollenaar/stockbot/internal/util/access"
 	"github.com/stollenaar/stockbot/internal/util/trackers"
 	"github.com/stollenaar/stockbot/internal/util/yfa"
 )
@@ -23,8 +29,15 @@ var (
 		Name:        "portfolio",
 		Description: "Portfolio interaction command",
 	}
+
+	provider yfa.MarketDataProvider = yfa.NewYahooProvider()
 )
 
+// SetProvider overrides the market data provider used by the portfolio command
+func SetProvider(p yfa.MarketDataProvider) {
+	provider = p
+}
+
 type PortfolioCommand struct {
 	Name        string
 	Description string
@@ -42,42 +55,412 @@ func init() {
 	}()
 }
 
-func (s PortfolioCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
-	err := event.DeferCreateMessage(util.ConfigFile.SetEphemeral() == discord.MessageFlagEphemeral)
+func (s PortfolioCommand) Handler(ctx context.Context, event *events.ApplicationCommandInteractionCreate) {
+	sub := event.SlashCommandInteractionData()
+	// the member role is a setting of the guild, so it is always shared
+	shared := sub.Bool("guild") || *sub.SubCommandName == "role"
+
+	// shared portfolios are shown to the whole channel
+	err := event.DeferCreateMessage(!shared && util.ConfigFile.SetEphemeral() == discord.MessageFlagEphemeral)
 
 	if err != nil {
 		slog.Error("Error deferring: ", slog.Any("err", err))
 		return
 	}
 
-	sub := event.SlashCommandInteractionData()
+	owner, err := access.Resolve(event.User().ID, event.GuildID(), shared)
+	var name string
+	if err == nil {
+		name, err = database.ParsePortfolioName(sub.String("portfolio"))
+	}
+	if err == nil {
+		switch *sub.SubCommandName {
+		case "show", "gains":
+			// everyone in the server may look at its shared portfolios
+		case "create", "delete", "currency", "role":
+			err = access.CheckManage(owner, event.Member())
+		default:
+			err = access.CheckEdit(ctx, owner, event.Member())
+		}
+	}
+	if err != nil {
+		response := access.ErrorMessage(err, err.Error())
+		_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content:         &response,
+			AllowedMentions: &discord.AllowedMentions{},
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+		return
+	}
 
 	switch *sub.SubCommandName {
 	case "add":
-		addHandler(sub, event)
+		addHandler(ctx, owner, name, sub, event)
 	case "show":
-		showHandler(event)
+		showHandler(ctx, owner, name, sub, event)
 	case "update":
-		addHandler(sub, event)
+		addHandler(ctx, owner, name, sub, event)
 	case "remove":
-		removeHandler(sub, event)
+		removeHandler(ctx, owner, name, sub, event)
+	case "currency":
+		currencyHandler(ctx, owner, sub, event)
+	case "buy":
+		tradeHandler(ctx, owner, name, database.TransactionBuy, sub, event)
+	case "sell":
+		tradeHandler(ctx, owner, name, database.TransactionSell, sub, event)
+	case "gains":
+		gainsHandler(ctx, owner, name, sub, event)
+	case "create":
+		createHandler(ctx, owner, sub, event)
+	case "delete":
+		deleteHandler(ctx, owner, name, event)
+	case "role":
+		roleHandler(ctx, owner, sub, event)
+	case "deposit":
+		cashHandler(ctx, owner, name, database.CashDeposit, sub, event)
+	case "withdraw":
+		cashHandler(ctx, owner, name, database.CashWithdrawal, sub, event)
+	case "dividend":
+		cashHandler(ctx, owner, name, database.CashDividend, sub, event)
+	}
+}
+
+// describe names the portfolio the way the messages refer to it
+func describe(owner access.Owner, name string) string {
+	if owner.Guild {
+		return fmt.Sprintf("the server's %s portfolio", name)
+	}
+	return fmt.Sprintf("your %s portfolio", name)
+}
+
+// portfolioErrorMessage explains a missing portfolio, other errors are explained like util.SymbolErrorMessage
+func portfolioErrorMessage(err error, owner access.Owner, name, fallback string) string {
+	if errors.Is(err, database.ErrUnknownPortfolio) {
+		if owner.Guild {
+			return fmt.Sprintf("The server has no portfolio named %s, create it with /portfolio create", name)
+		}
+		return fmt.Sprintf("You have no portfolio named %s, create it with /portfolio create", name)
 	}
+	return util.SymbolErrorMessage(err, fallback)
 }
 
-func addHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+func addHandler(ctx context.Context, owner access.Owner, name string, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
 	portfolio := database.Portfolio{
-		UserID: event.User().ID.String(),
-		Symbol: args.Options["symbol"].String(),
+		UserID: owner.ID,
+		Name:   name,
+		Symbol: yfa.NormalizeSymbol(args.Options["symbol"].String()),
 		Shares: args.Options["amount"].Float(),
 	}
 
-	err := portfolio.UpsertPortfolio()
+	err := portfolio.UpsertPortfolio(ctx)
 
-	response := "Successfully added the stock to your portfolio"
+	response := fmt.Sprintf("Successfully added the stock to %s", describe(owner, name))
 
 	if err != nil {
 		slog.Error("Error adding the stock:", slog.Any("err", err))
-		response = "error adding the stock"
+		response = portfolioErrorMessage(err, owner, name, "error adding the stock")
+	}
+
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content: &response,
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err))
+	}
+}
+
+func tradeHandler(ctx context.Context, owner access.Owner, name, kind string, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	transaction := database.Transaction{
+		UserID:    owner.ID,
+		Portfolio: name,
+		Symbol:    yfa.NormalizeSymbol(args.Options["symbol"].String()),
+		Type:      kind,
+		Quantity:  args.Options["quantity"].Float(),
+		Price:     util.Pointer(args.Options["price"].Float()),
+		Fees:      args.Float("fees"),
+	}
+
+	var err error
+	transaction.Date, err = parseDate(args)
+
+	var response string
+	if err != nil {
+		response = "the date needs to be a past date like 2024-01-31"
+	} else if err = database.AddTransaction(ctx, transaction); err != nil {
+		slog.Error("Error adding the transaction:", slog.Any("err", err))
+		response = portfolioErrorMessage(err, owner, name, fmt.Sprintf("error recording the %s", kind))
+		if errors.Is(err, database.ErrInsufficientShares) {
+			response = fmt.Sprintf("There aren't enough shares of %s in %s on %s for this %s and the sells after it", transaction.Symbol, describe(owner, name), transaction.Date.Format("2006-01-02"), kind)
+		}
+	} else {
+		verb := "Bought"
+		if kind == database.TransactionSell {
+			verb = "Sold"
+		}
+		response = fmt.Sprintf("%s %s %s at %.2f on %s in %s", verb, strconv.FormatFloat(transaction.Quantity, 'f', -1, 64), transaction.Symbol, *transaction.Price, transaction.Date.Format("2006-01-02"), describe(owner, name))
+	}
+
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content: &response,
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err))
+	}
+}
+
+// parseDate returns the date option, today when it is left out. Dates in the future are rejected.
+func parseDate(args discord.SlashCommandInteractionData) (time.Time, error) {
+	date, ok := args.OptString("date")
+	if !ok {
+		return yfa.NormalizeDate(time.Now().UTC()), nil
+	}
+	parsed, err := time.Parse("2006-01-02", date)
+	if err == nil && parsed.After(time.Now()) {
+		err = fmt.Errorf("date %s is in the future", date)
+	}
+	return parsed, err
+}
+
+func cashHandler(ctx context.Context, owner access.Owner, name, kind string, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	entry := database.CashEntry{
+		UserID:    owner.ID,
+		Portfolio: name,
+		Type:      kind,
+		Amount:    args.Float("amount"),
+		Currency:  strings.ToUpper(strings.TrimSpace(args.String("currency"))),
+		Symbol:    yfa.NormalizeSymbol(args.String("symbol")),
+	}
+
+	var err error
+	if entry.Currency == "" && kind != database.CashDividend {
+		// deposits and withdrawals default to the currency the totals are shown in
+		entry.Currency, err = database.GetHomeCurrency(ctx, owner.ID)
+		if err != nil {
+			slog.Error("Error fetching home currency:", slog.Any("err", err))
+		}
+	}
+	entry.Date, err = parseDate(args)
+
+	var response string
+	if err != nil {
+		response = "the date needs to be a past date like 2024-01-31"
+	} else if err = database.AddCash(ctx, entry); err != nil {
+		slog.Error("Error adding the cash entry:", slog.Any("err", err))
+		response = portfolioErrorMessage(err, owner, name, fmt.Sprintf("error recording the %s", kind))
+	} else {
+		response = fmt.Sprintf("Recorded a %s of %.2f %s in %s", kind, entry.Amount, entry.Currency, describe(owner, name))
+		if kind == database.CashDividend {
+			response = fmt.Sprintf("Recorded a %s dividend of %.2f %s in %s", entry.Symbol, entry.Amount, entry.Currency, describe(owner, name))
+		}
+	}
+
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content: &response,
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err))
+	}
+}
+
+func gainsHandler(ctx context.Context, owner access.Owner, name string, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	year := args.Int("year")
+	method, _ := database.ParseCostMethod(args.String("method"))
+
+	gains, err := database.GetRealizedGains(ctx, owner.ID, name, year, method)
+	if err != nil {
+		slog.Error("Error fetching realized gains:", slog.Any("err", err))
+		response := "error calculating the realized gains"
+		_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content: &response,
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+		return
+	}
+
+	if len(gains) == 0 {
+		response := fmt.Sprintf("No sells recorded in %s in %d", describe(owner, name), year)
+		_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content: &response,
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+		return
+	}
+
+	currencies := gainCurrencies(ctx, gains)
+	content, err := database.RealizedGainsCSV(gains, currencies)
+	if err != nil {
+		slog.Error("Error generating the gains CSV:", slog.Any("err", err))
+	}
+	file := discord.NewFile(fmt.Sprintf("realized-gains-%s-%d-%s.csv", name, year, method), "", bytes.NewReader(content))
+
+	components := []discord.LayoutComponent{generateGainsComponent(name, year, method, gains, currencies, file)}
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Components: &components,
+		Files:      []*discord.File{file},
+		Flags:      util.ConfigFile.SetComponentV2Flags(),
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err), slog.Any(". With body:", components))
+	}
+}
+
+// gainCurrencies looks up the trading currency of every sold symbol
+func gainCurrencies(ctx context.Context, gains []database.RealizedGain) map[string]string {
+	var symbols []string
+	for _, gain := range gains {
+		if !slices.Contains(symbols, gain.Symbol) {
+			symbols = append(symbols, gain.Symbol)
+		}
+	}
+
+	currencies := make(map[string]string, len(symbols))
+	infos, err := provider.Quotes(ctx, symbols)
+	if err != nil {
+		slog.Error("Error fetching stocks", slog.Any("err", err))
+	}
+	for _, symbol := range symbols {
+		currencies[symbol] = infos[symbol].Currency
+	}
+	return currencies
+}
+
+// gainTotals holds the realized gains of a symbol or currency split by holding period
+type gainTotals struct {
+	Short, Long float64
+	Unknown     int
+}
+
+func (g *gainTotals) add(gain database.RealizedGain) {
+	switch {
+	case !gain.CostKnown:
+		g.Unknown++
+	case gain.LongTerm():
+		g.Long += gain.Gain()
+	default:
+		g.Short += gain.Gain()
+	}
+}
+
+func (g gainTotals) String() string {
+	text := fmt.Sprintf("short-term %+.2f, long-term %+.2f", g.Short, g.Long)
+	if g.Unknown > 0 {
+		text += fmt.Sprintf(" (%d without a known cost)", g.Unknown)
+	}
+	return text
+}
+
+func generateGainsComponent(name string, year int, method database.CostMethod, gains []database.RealizedGain, currencies map[string]string, file *discord.File) discord.LayoutComponent {
+	var symbols, totalCurrencies []string
+	bySymbol := make(map[string]*gainTotals)
+	byCurrency := make(map[string]*gainTotals)
+	for _, gain := range gains {
+		if _, ok := bySymbol[gain.Symbol]; !ok {
+			symbols = append(symbols, gain.Symbol)
+			bySymbol[gain.Symbol] = &gainTotals{}
+		}
+		bySymbol[gain.Symbol].add(gain)
+
+		currency := currencies[gain.Symbol]
+		if _, ok := byCurrency[currency]; !ok {
+			totalCurrencies = append(totalCurrencies, currency)
+			byCurrency[currency] = &gainTotals{}
+		}
+		byCurrency[currency].add(gain)
+	}
+
+	var perSymbol, totals []string
+	for _, symbol := range symbols {
+		perSymbol = append(perSymbol, fmt.Sprintf("**%s:** %s %s", symbol, bySymbol[symbol], currencies[symbol]))
+	}
+	for _, currency := range totalCurrencies {
+		totals = append(totals, fmt.Sprintf("**%s:** %s", currency, byCurrency[currency]))
+	}
+
+	return discord.ContainerComponent{
+		Components: []discord.ContainerSubComponent{
+			discord.TextDisplayComponent{
+				Content: fmt.Sprintf("# Realized Gains %d\n-# %s portfolio, %s matching, held over a year counts as long-term", year, name, method),
+			},
+			discord.SeparatorComponent{
+				Divider: util.Pointer(true),
+			},
+			discord.TextDisplayComponent{
+				Content: strings.Join(perSymbol, "\n"),
+			},
+			discord.SeparatorComponent{
+				Divider: util.Pointer(true),
+			},
+			discord.TextDisplayComponent{
+				Content: "**Total**\n" + strings.Join(totals, "\n"),
+			},
+			discord.FileComponent{
+				File: discord.UnfurledMediaItem{
+					URL: fmt.Sprintf("attachment://%s", file.Name),
+				},
+			},
+		},
+	}
+}
+
+func currencyHandler(ctx context.Context, owner access.Owner, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	currency := strings.ToUpper(strings.TrimSpace(args.Options["code"].String()))
+	err := database.SetHomeCurrency(ctx, owner.ID, currency)
+
+	response := fmt.Sprintf("Your portfolio totals are now shown in %s", currency)
+	if owner.Guild {
+		response = fmt.Sprintf("The server's portfolio totals are now shown in %s", currency)
+	}
+
+	if err != nil {
+		slog.Error("Error setting the home currency:", slog.Any("err", err))
+		response = fmt.Sprintf("%s isn't a currency with a known exchange rate", currency)
+	}
+
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content: &response,
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err))
+	}
+}
+
+func createHandler(ctx context.Context, owner access.Owner, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	name, err := database.ParsePortfolioName(args.String("name"))
+	if err == nil {
+		err = database.CreatePortfolio(ctx, owner.ID, name)
+	}
+
+	response := fmt.Sprintf("Created %s, pick it with the portfolio option", describe(owner, name))
+
+	if errors.Is(err, database.ErrPortfolioExists) {
+		response = fmt.Sprintf("There already is a portfolio named %s", name)
+	} else if err != nil {
+		slog.Error("Error creating the portfolio:", slog.Any("err", err))
+		response = fmt.Sprintf("error creating the portfolio: %s", err)
+	}
+
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content: &response,
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err))
+	}
+}
+
+func deleteHandler(ctx context.Context, owner access.Owner, name string, event *events.ApplicationCommandInteractionCreate) {
+	err := database.DeletePortfolio(ctx, owner.ID, name)
+
+	response := fmt.Sprintf("Deleted %s with its holdings and trades", describe(owner, name))
+
+	if err != nil {
+		slog.Error("Error deleting the portfolio:", slog.Any("err", err))
+		response = portfolioErrorMessage(err, owner, name, "error deleting the portfolio")
 	}
 
 	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
@@ -88,19 +471,44 @@ func addHandler(args discord.SlashCommandInteractionData, event *events.Applicat
 	}
 }
 
-func showHandler(event *events.ApplicationCommandInteractionCreate) {
-	portfolios, err := database.GetCompletePortfolio(event.User().ID.String())
+func roleHandler(ctx context.Context, owner access.Owner, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	role := args.Snowflake("role")
+	err := database.SetGuildMemberRole(ctx, owner.ID, role.String())
+
+	response := fmt.Sprintf("Members with the <@&%s> role can now change the shared portfolios and watchlists of this server", role)
+
+	if err != nil {
+		slog.Error("Error setting the member role:", slog.Any("err", err))
+		response = "error setting the member role"
+	}
+
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Content:         &response,
+		AllowedMentions: &discord.AllowedMentions{},
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err))
+	}
+}
+
+func showHandler(ctx context.Context, owner access.Owner, name string, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	portfolios, err := database.GetCompletePortfolio(ctx, owner.ID, name)
 
 	if err != nil {
 		slog.Error("Error fetching portfolio:", slog.Any("err", err))
 	}
 
+	balances, err := database.GetCashBalances(ctx, owner.ID, name)
+	if err != nil {
+		slog.Error("Error fetching cash balances:", slog.Any("err", err))
+	}
+
 	var components []discord.LayoutComponent
 
-	if len(portfolios) == 0 {
+	if len(portfolios) == 0 && len(balances) == 0 {
 		components = append(components,
 			discord.TextDisplayComponent{
-				Content: "No stock in your portfolio yet",
+				Content: fmt.Sprintf("No stock in %s yet", describe(owner, name)),
 			})
 
 		_, err := event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
@@ -112,7 +520,20 @@ func showHandler(event *events.ApplicationCommandInteractionCreate) {
 		}
 		return
 	}
-	components, files := generateComponents("1y", portfolios)
+
+	home, err := database.GetHomeCurrency(ctx, owner.ID)
+	if err != nil {
+		slog.Error("Error fetching home currency:", slog.Any("err", err))
+	}
+
+	method, _ := database.ParseCostMethod(args.String("method"))
+
+	cash := make([]valuation, 0, len(balances))
+	for _, currency := range slices.Sorted(maps.Keys(balances)) {
+		cash = append(cash, convert(ctx, balances[currency], currency, home))
+	}
+
+	components, files := generateComponents(ctx, owner, name, "1y", method, portfolios, cash, home)
 
 	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
 		Components: &components,
@@ -124,8 +545,8 @@ func showHandler(event *events.ApplicationCommandInteractionCreate) {
 	}
 }
 
-func removeHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
-	err := database.RemovePortfolio(event.User().ID.String(), strings.ToUpper(args.Options["symbol"].String()))
+func removeHandler(ctx context.Context, owner access.Owner, name string, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	err := database.RemovePortfolio(ctx, owner.ID, name, yfa.NormalizeSymbol(args.Options["symbol"].String()))
 	response := "Successfully removed the stock from the portfolio"
 
 	if err != nil {
@@ -148,35 +569,67 @@ func (s PortfolioCommand) CreateCommandArguments() []discord.ApplicationCommandO
 			Description: "add a stock to your portfolio",
 			Options: []discord.ApplicationCommandOption{
 				discord.ApplicationCommandOptionString{
-					Name:        "symbol",
-					Description: "stock symbol",
-					Required:    true,
+					Name:         "symbol",
+					Description:  "stock symbol",
+					Required:     true,
+					Autocomplete: true,
 				},
 				discord.ApplicationCommandOptionFloat{
 					Name:        "amount",
 					Description: "Number of shares you have",
 					Required:    true,
 				},
+				portfolioOption,
+				guildOption,
 			},
 		},
 		discord.ApplicationCommandOptionSubCommand{
 			Name:        "show",
 			Description: "show your portfolio",
+			Options: []discord.ApplicationCommandOption{
+				portfolioOption,
+				guildOption,
+				costMethodOption,
+			},
+		},
+		tradeSubCommand("buy", "record a buy of a stock"),
+		tradeSubCommand("sell", "record a sell of a stock"),
+		cashSubCommand("deposit", "record money paid into the portfolio", false),
+		cashSubCommand("withdraw", "record money taken out of the portfolio", false),
+		cashSubCommand("dividend", "record a dividend received for a stock", true),
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "gains",
+			Description: "report the realized gains of a tax year, with a CSV export",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionInt{
+					Name:        "year",
+					Description: "tax year, like 2024",
+					Required:    true,
+					MinValue:    util.Pointer(1970),
+					MaxValue:    util.Pointer(9999),
+				},
+				portfolioOption,
+				guildOption,
+				costMethodOption,
+			},
 		},
 		discord.ApplicationCommandOptionSubCommand{
 			Name:        "update",
 			Description: "update a stock in your portfolio",
 			Options: []discord.ApplicationCommandOption{
 				discord.ApplicationCommandOptionString{
-					Name:        "symbol",
-					Description: "stock symbol",
-					Required:    true,
+					Name:         "symbol",
+					Description:  "stock symbol",
+					Required:     true,
+					Autocomplete: true,
 				},
 				discord.ApplicationCommandOptionFloat{
 					Name:        "amount",
 					Description: "Number of shares you have",
 					Required:    true,
 				},
+				portfolioOption,
+				guildOption,
 			},
 		},
 		discord.ApplicationCommandOptionSubCommand{
@@ -184,21 +637,238 @@ func (s PortfolioCommand) CreateCommandArguments() []discord.ApplicationCommandO
 			Description: "remove a stock from your portfolio",
 			Options: []discord.ApplicationCommandOption{
 				discord.ApplicationCommandOptionString{
-					Name:        "symbol",
-					Description: "stock symbol",
+					Name:         "symbol",
+					Description:  "stock symbol",
+					Required:     true,
+					Autocomplete: true,
+				},
+				portfolioOption,
+				guildOption,
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "create",
+			Description: "create a named portfolio, like retirement or trading",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "name",
+					Description: "portfolio name",
+					Required:    true,
+					MaxLength:   util.Pointer(32),
+				},
+				guildOption,
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "delete",
+			Description: "delete a portfolio with its holdings and trades",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:         "portfolio",
+					Description:  "portfolio name",
+					Required:     true,
+					Autocomplete: true,
+				},
+				guildOption,
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "currency",
+			Description: "set the home currency your portfolio totals are shown in",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:        "code",
+					Description: "ISO currency code, like USD or EUR",
 					Required:    true,
+					MinLength:   util.Pointer(3),
+					MaxLength:   util.Pointer(3),
 				},
+				guildOption,
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "role",
+			Description: "set the role whose members can change the shared portfolios and watchlists of this server",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionRole{
+					Name:        "role",
+					Description: "member role, like the members of the investment club",
+					Required:    true,
+				},
+			},
+		},
+	}
+}
+
+// portfolioOption picks one of the named portfolios of the user
+var portfolioOption = discord.ApplicationCommandOptionString{
+	Name:         "portfolio",
+	Description:  "portfolio name, defaults to your default portfolio",
+	Required:     false,
+	Autocomplete: true,
+}
+
+// guildOption switches a subcommand to the shared portfolios of the server
+var guildOption = discord.ApplicationCommandOptionBool{
+	Name:        "guild",
+	Description: "use the shared portfolios of this server",
+	Required:    false,
+}
+
+// costMethodOption selects how sells are matched against the lots
+var costMethodOption = discord.ApplicationCommandOptionString{
+	Name:        "method",
+	Description: "how sells are matched against buys, defaults to FIFO",
+	Required:    false,
+	Choices: []discord.ApplicationCommandOptionChoiceString{
+		{Name: "FIFO", Value: string(database.CostFIFO)},
+		{Name: "Average cost", Value: string(database.CostAverage)},
+	},
+}
+
+func tradeSubCommand(name, description string) discord.ApplicationCommandOptionSubCommand {
+	return discord.ApplicationCommandOptionSubCommand{
+		Name:        name,
+		Description: description,
+		Options: []discord.ApplicationCommandOption{
+			discord.ApplicationCommandOptionString{
+				Name:         "symbol",
+				Description:  "stock symbol",
+				Required:     true,
+				Autocomplete: true,
+			},
+			discord.ApplicationCommandOptionFloat{
+				Name:        "quantity",
+				Description: "number of shares",
+				Required:    true,
+				MinValue:    util.Pointer(0.000001),
+			},
+			discord.ApplicationCommandOptionFloat{
+				Name:        "price",
+				Description: "price per share",
+				Required:    true,
+				MinValue:    util.Pointer(0.0),
+			},
+			discord.ApplicationCommandOptionString{
+				Name:        "date",
+				Description: "trade date (YYYY-MM-DD), defaults to today",
+				Required:    false,
 			},
+			discord.ApplicationCommandOptionFloat{
+				Name:        "fees",
+				Description: "commission and fees paid",
+				Required:    false,
+				MinValue:    util.Pointer(0.0),
+			},
+			portfolioOption,
+			guildOption,
 		},
 	}
 }
 
-func (s PortfolioCommand) ComponentHandler(event *events.ComponentInteractionCreate) {
-	if event.Message.Interaction.User.ID != event.Member().User.ID {
+// cashSubCommand is a subcommand adding a line to the cash ledger, a dividend also names its stock
+func cashSubCommand(name, description string, dividend bool) discord.ApplicationCommandOptionSubCommand {
+	var options []discord.ApplicationCommandOption
+	currency := "ISO currency code, defaults to the home currency"
+	if dividend {
+		options = append(options, discord.ApplicationCommandOptionString{
+			Name:         "symbol",
+			Description:  "stock symbol that paid the dividend",
+			Required:     true,
+			Autocomplete: true,
+		})
+		currency = "ISO currency code, defaults to the currency the stock trades in"
+	}
+
+	options = append(options,
+		discord.ApplicationCommandOptionFloat{
+			Name:        "amount",
+			Description: "amount of money",
+			Required:    true,
+			MinValue:    util.Pointer(0.01),
+		},
+		discord.ApplicationCommandOptionString{
+			Name:        "currency",
+			Description: currency,
+			Required:    false,
+			MinLength:   util.Pointer(3),
+			MaxLength:   util.Pointer(3),
+		},
+		discord.ApplicationCommandOptionString{
+			Name:        "date",
+			Description: "date (YYYY-MM-DD), defaults to today",
+			Required:    false,
+		},
+		portfolioOption,
+		guildOption,
+	)
+
+	return discord.ApplicationCommandOptionSubCommand{
+		Name:        name,
+		Description: description,
+		Options:     options,
+	}
+}
+
+// AutocompleteHandler suggests the tickers matching the symbol or the portfolios matching the name being typed
+func (s PortfolioCommand) AutocompleteHandler(ctx context.Context, event *events.AutocompleteInteractionCreate) {
+	choices := []discord.AutocompleteChoice{}
+	switch event.Data.Focused().Name {
+	case "symbol":
+		choices = util.SymbolChoices(ctx, provider, event.Data.String("symbol"))
+	case "portfolio":
+		if owner, err := access.Resolve(event.User().ID, event.GuildID(), event.Data.Bool("guild")); err == nil {
+			choices = portfolioChoices(ctx, owner.ID, event.Data.String("portfolio"))
+		}
+	}
+
+	if err := event.AutocompleteResult(choices); err != nil {
+		slog.Error("Error sending autocomplete result:", slog.Any("err", err))
+	}
+}
+
+// portfolioChoices lists the portfolios of the owner containing the typed text, the default portfolio included
+func portfolioChoices(ctx context.Context, ownerID, typed string) []discord.AutocompleteChoice {
+	headers, err := database.GetPortfolioHeaders(ctx, ownerID)
+	if err != nil {
+		slog.Error("Error fetching portfolios:", slog.Any("err", err))
+	}
+
+	names := []string{database.DefaultPortfolio}
+	for _, header := range headers {
+		if header.Name != database.DefaultPortfolio {
+			names = append(names, header.Name)
+		}
+	}
+
+	typed = strings.ToLower(strings.TrimSpace(typed))
+	choices := []discord.AutocompleteChoice{}
+	for _, name := range names {
+		if len(choices) == 25 {
+			break
+		}
+		if strings.Contains(name, typed) {
+			choices = append(choices, discord.AutocompleteChoiceString{Name: name, Value: name})
+		}
+	}
+	return choices
+}
+
+func (s PortfolioCommand) ComponentHandler(ctx context.Context, event *events.ComponentInteractionCreate) {
+	details := strings.Split(event.Data.CustomID(), ";")
+
+	// anyone in the channel may page through a shared portfolio, a personal one only its owner
+	shared := len(details) > 6 && details[6] == "guild"
+	if !shared && event.Message.Interaction.User.ID != event.User().ID {
+		return
+	}
+	owner, err := access.Resolve(event.User().ID, event.GuildID(), shared)
+	if err != nil {
+		slog.Error("Error resolving the portfolio owner: ", slog.Any("err", err))
 		return
 	}
 
-	err := event.DeferUpdateMessage()
+	err = event.DeferUpdateMessage()
 
 	if err != nil {
 		slog.Error("Error deferring: ", slog.Any("err", err))
@@ -206,23 +876,57 @@ func (s PortfolioCommand) ComponentHandler(event *events.ComponentInteractionCre
 	}
 
 	components := event.Message.Components
-	details := strings.Split(event.Data.CustomID(), ";")
 
 	pIndex, _ := strconv.Atoi(details[1])
-	portfolio, err := database.GetPortfolio(event.Member().User.ID.String(), details[2])
+	name := database.DefaultPortfolio
+	if len(details) > 5 {
+		name = details[5]
+	}
+	portfolio, err := database.GetPortfolio(ctx, owner.ID, name, details[2])
 
 	if err != nil {
 		slog.Error("Error fetching portfolio: ", slog.Any("err", err))
 		return
 	}
 
-	component, file := generateComponent(pIndex, details[3], portfolio)
+	infos, err := provider.Quotes(ctx, []string{portfolio.Symbol})
+	if err != nil {
+		slog.Error("Error fetching stock", slog.Any("err", err))
+		return
+	}
+
+	home, err := database.GetHomeCurrency(ctx, owner.ID)
+	if err != nil {
+		slog.Error("Error fetching home currency:", slog.Any("err", err))
+	}
+
+	method := database.CostFIFO
+	if len(details) > 4 {
+		method, _ = database.ParseCostMethod(details[4])
+	}
+	transactions, err := database.GetTransactions(ctx, portfolio.UserID, portfolio.Name, portfolio.Symbol)
+	if err != nil {
+		slog.Error("Error fetching transactions: ", slog.Any("err", err))
+	}
+
+	info := infos[portfolio.Symbol]
+	holding := database.BuildHolding(portfolio.Symbol, transactions, method)
+	component, file := generateComponent(ctx, owner, pIndex, details[3], method, portfolio, info, valuePosition(ctx, portfolio, info, home), holding)
 	components[pIndex] = component
 
 	var attachments []discord.AttachmentUpdate
 	for i, attachment := range components {
 		if i != pIndex {
-			id := attachment.(discord.ContainerComponent).Components[2].(discord.SectionComponent).Accessory.(discord.ThumbnailComponent).Media.AttachmentID
+			// the totals container has no chart
+			container := attachment.(discord.ContainerComponent)
+			if len(container.Components) < 3 {
+				continue
+			}
+			section, ok := container.Components[2].(discord.SectionComponent)
+			if !ok {
+				continue
+			}
+			id := section.Accessory.(discord.ThumbnailComponent).Media.AttachmentID
 			if id == snowflake.MustParse("0") {
 				continue
 			}
@@ -246,7 +950,46 @@ func (s PortfolioCommand) ComponentHandler(event *events.ComponentInteractionCre
 
 }
 
-func generateComponents(period string, portfolio []database.Portfolio) (components []discord.LayoutComponent, files []*discord.File) {
+func generateComponents(ctx context.Context, owner access.Owner, name, period string, method database.CostMethod, portfolio []database.Portfolio, cash []valuation, home string) (components []discord.LayoutComponent, files []*discord.File) {
+	if len(portfolio) == 0 {
+		return []discord.LayoutComponent{generateTotalComponent(name, portfolio, nil, cash, home)}, nil
+	}
+
+	// get the latest quotes for the whole portfolio in one round trip
+	symbols := make([]string, 0, len(portfolio))
+	for _, p := range portfolio {
+		symbols = append(symbols, p.Symbol)
+	}
+	infos, err := provider.Quotes(ctx, symbols)
+	if err != nil {
+		slog.Error("Error fetching stocks", slog.Any("err", err))
+		// still show the cash, every holding is listed as not included
+		values := make([]valuation, len(portfolio))
+		for i := range values {
+			values[i] = valuation{Home: home}
+		}
+		return []discord.LayoutComponent{
+			discord.TextDisplayComponent{Content: "Could not fetch the latest prices, try again later"},
+			generateTotalComponent(name, portfolio, values, cash, home),
+		}, nil
+	}
+
+	values := make([]valuation, len(portfolio))
+	for i, p := range portfolio {
+		values[i] = valuePosition(ctx, p, infos[p.Symbol], home)
+	}
+
+	transactions := make(map[string][]database.Transaction)
+	if len(portfolio) > 0 {
+		all, err := database.GetPortfolioTransactions(ctx, portfolio[0].UserID, portfolio[0].Name)
+		if err != nil {
+			slog.Error("Error fetching transactions", slog.Any("err", err))
+		}
+		for _, t := range all {
+			transactions[t.Symbol] = append(transactions[t.Symbol], t)
+		}
+	}
+
 	// bounded concurrency
 	const maxConcurrent = 4
 	sem := make(chan struct{}, maxConcurrent)
@@ -261,7 +1004,8 @@ func generateComponents(period string, portfolio []database.Portfolio) (componen
 		sem <- struct{}{}
 		go func(idx int, item database.Portfolio) {
 			defer func() { <-sem }()
-			component, file := generateComponent(idx, period, item)
+			holding := database.BuildHolding(item.Symbol, transactions[item.Symbol], method)
+			component, file := generateComponent(ctx, owner, idx, period, method, item, infos[item.Symbol], values[idx], holding)
 			out <- res{idx: idx, component: component, file: file}
 		}(i, p)
 	}
@@ -283,21 +1027,135 @@ func generateComponents(period string, portfolio []database.Portfolio) (componen
 		components = append(components, r.component)
 		files = append(files, r.file)
 	}
+	components = append(components, generateTotalComponent(name, portfolio, values, cash, home))
 	return
 }
 
-func generateComponent(pIndex int, period string, portfolio database.Portfolio) (component discord.LayoutComponent, file *discord.File) {
+// valuation is the value of a position in its own currency and converted into the home currency
+type valuation struct {
+	Value     float64
+	Currency  string
+	Converted float64
+	Home      string
+	Rate      database.FXRate
+	// Converted is only set when a rate was found
+	Ok bool
+}
+
+// valuePosition values the position at the latest price and converts it into the home currency
+func valuePosition(ctx context.Context, portfolio database.Portfolio, info yfa.YahooTickerInfo, home string) valuation {
+	if info.RegularMarketPrice == nil {
+		return valuation{Home: home}
+	}
+	return convert(ctx, portfolio.Shares*info.RegularMarketPrice.Raw, info.Currency, home)
+}
 
-	ticker := yfa.NewTicker(portfolio.Symbol)
-	// get the latest PriceData
-	info, err := ticker.Info()
+// convert values an amount of currency in the home currency
+func convert(ctx context.Context, amount float64, currency, home string) (value valuation) {
+	value = valuation{Value: amount, Currency: currency, Home: home}
 
+	rate, err := database.GetFXRate(ctx, currency, home)
 	if err != nil {
-		slog.Error("Error fetching stock", slog.Any("err", err))
+		slog.Error("Error fetching fx rate", slog.Any("err", err), slog.String("from", currency), slog.String("to", home))
+		return
+	}
+	value.Rate = rate
+	value.Converted = rate.Convert(amount)
+	value.Ok = true
+	return
+}
+
+// String shows the value and, when the currencies differ, the converted value with the rate date
+func (v valuation) String() string {
+	text := fmt.Sprintf("**Value:** %.2f %s", v.Value, v.Currency)
+	if !v.Ok {
+		if v.Currency != v.Home {
+			text += fmt.Sprintf("\n-# no %s rate available", v.Home)
+		}
+		return text
+	}
+	if v.Currency != v.Home {
+		text += fmt.Sprintf("\n≈ %.2f %s\n-# at %.4f on %s", v.Converted, v.Home, v.Rate.Rate, v.Rate.Date.Format("2006-01-02"))
+	}
+	return text
+}
+
+// costBasis shows the cost of the open lots and the unrealized gain at the current price
+func costBasis(holding database.Holding, info yfa.YahooTickerInfo, method database.CostMethod) string {
+	cost, known := holding.CostBasis()
+	if known <= 0 {
+		return "**Cost Basis:** unknown\n-# record trades with /portfolio buy and sell"
+	}
+
+	text := fmt.Sprintf("**Cost Basis (%s):** %.2f %s\n-# %.2f per share", method, cost, info.Currency, cost/known)
+	if info.RegularMarketPrice != nil {
+		gain := holding.UnrealizedGain(info.RegularMarketPrice.Raw)
+		text += fmt.Sprintf("\n**Unrealized P/L:** %+.2f %s (%+.2f%%)", gain, info.Currency, gain/cost*100)
+	}
+	if unknown := holding.Shares() - known; unknown > 0.0001 {
+		text += fmt.Sprintf("\n-# excludes %.2f shares without a known cost", unknown)
+	}
+	return text
+}
+
+// generateTotalComponent sums the converted positions and cash into the total equity and lists the rates used
+func generateTotalComponent(name string, portfolio []database.Portfolio, values []valuation, cash []valuation, home string) discord.LayoutComponent {
+	var holdings, balance float64
+	var rates, missing, balances []string
+	seen := make(map[string]bool)
+	addRate := func(value valuation) {
+		if value.Currency != home && !seen[value.Currency] {
+			seen[value.Currency] = true
+			rates = append(rates, fmt.Sprintf("%s→%s %.4f on %s", value.Currency, home, value.Rate.Rate, value.Rate.Date.Format("2006-01-02")))
+		}
+	}
+
+	for i, value := range values {
+		if !value.Ok {
+			missing = append(missing, portfolio[i].Symbol)
+			continue
+		}
+		holdings += value.Converted
+		addRate(value)
+	}
+	for _, value := range cash {
+		balances = append(balances, fmt.Sprintf("%.2f %s", value.Value, value.Currency))
+		if !value.Ok {
+			missing = append(missing, value.Currency+" cash")
+			continue
+		}
+		balance += value.Converted
+		addRate(value)
+	}
+
+	content := fmt.Sprintf("# Total\n-# %s portfolio\n**Holdings:** %.2f %s\n**Cash:** %.2f %s", name, holdings, home, balance, home)
+	if len(cash) > 1 || (len(cash) == 1 && cash[0].Currency != home) {
+		content += "\n-# " + strings.Join(balances, ", ")
+	}
+	content += fmt.Sprintf("\n**Total Equity:** %.2f %s", holdings+balance, home)
+	if len(rates) > 0 {
+		content += "\n-# converted at " + strings.Join(rates, ", ")
+	}
+	if len(missing) > 0 {
+		content += "\n-# not included, no price or rate: " + strings.Join(missing, ", ")
+	}
+
+	return discord.ContainerComponent{
+		Components: []discord.ContainerSubComponent{
+			discord.TextDisplayComponent{
+				Content: content,
+			},
+		},
+	}
+}
+
+func generateComponent(ctx context.Context, owner access.Owner, pIndex int, period string, method database.CostMethod, portfolio database.Portfolio, info yfa.YahooTickerInfo, value valuation, holding database.Holding) (component discord.LayoutComponent, file *discord.File) {
+	if info.RegularMarketPrice == nil {
+		slog.Error("No quote found for stock", slog.String("symbol", portfolio.Symbol))
 		return
 	}
 
-	hist, err := trackers.FetchHistory(ticker, period)
+	hist, err := trackers.FetchHistory(ctx, provider, portfolio.Symbol, period)
 
 	if err != nil {
 		slog.Error("Error fetching history", slog.Any("err", err))
@@ -312,6 +1170,11 @@ func generateComponent(pIndex int, period string, portfolio database.Portfolio)
 
 	shares := fmt.Sprintf("%.2f", portfolio.Shares)
 
+	scope := "user"
+	if owner.Guild {
+		scope = "guild"
+	}
+
 	var color int
 	if info.RegularMarketChangePercent.Raw > 0 {
 		color = 5763719
@@ -332,10 +1195,10 @@ func generateComponent(pIndex int, period string, portfolio database.Portfolio)
 			discord.SectionComponent{
 				Components: []discord.SectionSubComponent{
 					discord.TextDisplayComponent{
-						Content: fmt.Sprintf("**Amount of Shares:** %s\n~~%s~~", shares, strings.Repeat(" ", 18+len(shares))),
+						Content: fmt.Sprintf("**Amount of Shares:** %s\n%s\n~~%s~~", shares, costBasis(holding, info, method), strings.Repeat(" ", 18+len(shares))),
 					},
 					discord.TextDisplayComponent{
-						Content: fmt.Sprintf("**Price:**\n%s%s %s", info.CurrencySymbol, info.RegularMarketPrice.Fmt, info.Currency),
+						Content: fmt.Sprintf("**Price:**\n%s%s %s\n%s", info.CurrencySymbol, info.RegularMarketPrice.Fmt, info.Currency, value),
 					},
 					discord.TextDisplayComponent{
 						Content: fmt.Sprintf("**Daily %% Change:** %s\n**Weekly %% Change:** %s\n**Yearly %% Change:** %s", info.RegularMarketChangePercent.Fmt, trackers.PeriodChange("1wk", hist.Yearly), trackers.PeriodChange("1y", hist.Yearly)),
@@ -354,27 +1217,27 @@ func generateComponent(pIndex int, period string, portfolio database.Portfolio)
 				Components: util.GenerateButtons(
 					[]util.Button{
 						{
-							ID:     fmt.Sprintf("portfolio;%d;%s;%s", pIndex, info.Symbol, "1d"),
+							ID:     fmt.Sprintf("portfolio;%d;%s;%s;%s;%s;%s", pIndex, info.Symbol, "1d", method, portfolio.Name, scope),
 							Label:  "Daily",
 							Active: period == "1d",
 						},
 						{
-							ID:     fmt.Sprintf("portfolio;%d;%s;%s", pIndex, info.Symbol, "1wk"),
+							ID:     fmt.Sprintf("portfolio;%d;%s;%s;%s;%s;%s", pIndex, info.Symbol, "1wk", method, portfolio.Name, scope),
 							Label:  "1 Week",
 							Active: period == "1wk",
 						},
 						{
-							ID:     fmt.Sprintf("portfolio;%d;%s;%s", pIndex, info.Symbol, "1mo"),
+							ID:     fmt.Sprintf("portfolio;%d;%s;%s;%s;%s;%s", pIndex, info.Symbol, "1mo", method, portfolio.Name, scope),
 							Label:  "1 Month",
 							Active: period == "1mo",
 						},
 						{
-							ID:     fmt.Sprintf("portfolio;%d;%s;%s", pIndex, info.Symbol, "3mo"),
+							ID:     fmt.Sprintf("portfolio;%d;%s;%s;%s;%s;%s", pIndex, info.Symbol, "3mo", method, portfolio.Name, scope),
 							Label:  "3 Month",
 							Active: period == "3mo",
 						},
 						{
-							ID:     fmt.Sprintf("portfolio;%d;%s;%s", pIndex, info.Symbol, "1y"),
+							ID:     fmt.Sprintf("portfolio;%d;%s;%s;%s;%s;%s", pIndex, info.Symbol, "1y", method, portfolio.Name, scope),
 							Label:  "1 Year",
 							Active: period == "1y",
 						},
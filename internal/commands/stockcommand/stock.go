@@ -1,13 +1,19 @@
 package stockcommand
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	"github.com/stollenaar/stockbot/internal/util"
+	"github.com/stollenaar/stockbot/internal/util/pricing"
 	"github.com/stollenaar/stockbot/internal/util/trackers"
 	"github.com/stollenaar/stockbot/internal/util/yfa"
 )
@@ -22,14 +28,21 @@ var (
 		Name:        "stock",
 		Description: "Stock interaction command",
 	}
+
+	provider yfa.MarketDataProvider = yfa.NewYahooProvider()
 )
 
+// SetProvider overrides the market data provider used by the stock command
+func SetProvider(p yfa.MarketDataProvider) {
+	provider = p
+}
+
 type StockCommand struct {
 	Name        string
 	Description string
 }
 
-func (s StockCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
+func (s StockCommand) Handler(ctx context.Context, event *events.ApplicationCommandInteractionCreate) {
 	err := event.DeferCreateMessage(util.ConfigFile.SetEphemeral() == discord.MessageFlagEphemeral)
 
 	if err != nil {
@@ -41,7 +54,13 @@ func (s StockCommand) Handler(event *events.ApplicationCommandInteractionCreate)
 
 	switch *sub.SubCommandName {
 	case "show":
-		showHandler(sub, event)
+		showHandler(ctx, sub, event)
+	case "fundamentals":
+		fundamentalsHandler(ctx, sub, event)
+	case "options":
+		optionsHandler(ctx, sub, event)
+	case "news":
+		newsHandler(ctx, sub, event)
 	case "alert":
 
 	}
@@ -54,16 +73,74 @@ func (s StockCommand) CreateCommandArguments() []discord.ApplicationCommandOptio
 			Description: "show stock information",
 			Options: []discord.ApplicationCommandOption{
 				discord.ApplicationCommandOptionString{
-					Name:        "symbol",
-					Description: "stock symbol",
-					Required:    true,
+					Name:         "symbol",
+					Description:  "stock symbol",
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "options",
+			Description: "show the option chain of a stock",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:         "symbol",
+					Description:  "stock symbol",
+					Required:     true,
+					Autocomplete: true,
+				},
+				discord.ApplicationCommandOptionString{
+					Name:         "expiration",
+					Description:  "expiration date (YYYY-MM-DD), defaults to the nearest",
+					Required:     false,
+					Autocomplete: true,
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "fundamentals",
+			Description: "show valuation, dividend and earnings data of a stock",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:         "symbol",
+					Description:  "stock symbol",
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		discord.ApplicationCommandOptionSubCommand{
+			Name:        "news",
+			Description: "show the latest headlines about a stock",
+			Options: []discord.ApplicationCommandOption{
+				discord.ApplicationCommandOptionString{
+					Name:         "symbol",
+					Description:  "stock symbol",
+					Required:     true,
+					Autocomplete: true,
 				},
 			},
 		},
 	}
 }
 
-func (s StockCommand) ComponentHandler(event *events.ComponentInteractionCreate) {
+// AutocompleteHandler suggests the tickers matching the symbol being typed, and its option expirations
+func (s StockCommand) AutocompleteHandler(ctx context.Context, event *events.AutocompleteInteractionCreate) {
+	choices := []discord.AutocompleteChoice{}
+	switch event.Data.Focused().Name {
+	case "symbol":
+		choices = util.SymbolChoices(ctx, provider, event.Data.String("symbol"))
+	case "expiration":
+		choices = expirationChoices(ctx, yfa.NormalizeSymbol(event.Data.String("symbol")), event.Data.String("expiration"))
+	}
+
+	if err := event.AutocompleteResult(choices); err != nil {
+		slog.Error("Error sending autocomplete result:", slog.Any("err", err))
+	}
+}
+
+func (s StockCommand) ComponentHandler(ctx context.Context, event *events.ComponentInteractionCreate) {
 	if event.Message.Interaction.User.ID != event.Member().User.ID {
 		return
 	}
@@ -77,11 +154,29 @@ func (s StockCommand) ComponentHandler(event *events.ComponentInteractionCreate)
 
 	details := strings.Split(event.Data.CustomID(), ";")
 
-	component, file := generateComponent(details[1], details[2])
+	var component discord.LayoutComponent
+	var file *discord.File
+	if details[2] == "options" {
+		data, ok := event.Data.(discord.StringSelectMenuInteractionData)
+		if !ok || len(data.Values) == 0 {
+			return
+		}
+		component, file, err = generateOptionsComponent(ctx, details[1], data.Values[0])
+		if err != nil {
+			slog.Error("Error generating the option chain", slog.Any("err", err), slog.String("symbol", details[1]))
+			return
+		}
+	} else {
+		component, file = generateComponent(ctx, details[1], details[2])
+	}
 
+	var files []*discord.File
+	if file != nil {
+		files = append(files, file)
+	}
 	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
 		Components: &[]discord.LayoutComponent{component},
-		Files:      []*discord.File{file},
+		Files:      files,
 		Flags:      util.ConfigFile.SetComponentV2Flags(),
 	})
 	if err != nil {
@@ -89,10 +184,10 @@ func (s StockCommand) ComponentHandler(event *events.ComponentInteractionCreate)
 	}
 }
 
-func showHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+func showHandler(ctx context.Context, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
 	symbol := strings.ToUpper(args.Options["symbol"].String())
 	// embeds := getShowEmbed(symbol)
-	component, file := generateComponent(symbol, "1y")
+	component, file := generateComponent(ctx, symbol, "1y")
 
 	_, err := event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
 		// Embeds: &embeds,
@@ -105,18 +200,17 @@ func showHandler(args discord.SlashCommandInteractionData, event *events.Applica
 	}
 }
 
-func generateComponent(symbol, period string) (component discord.LayoutComponent, file *discord.File) {
+func generateComponent(ctx context.Context, symbol, period string) (component discord.LayoutComponent, file *discord.File) {
 
-	ticker := yfa.NewTicker(symbol)
 	// get the latest PriceData
-	info, err := ticker.Info()
+	info, err := provider.Info(ctx, symbol)
 
 	if err != nil {
 		slog.Error("Error fetching stock", slog.Any("err", err))
 		return
 	}
 
-	hist, err := trackers.FetchHistory(ticker, period)
+	hist, err := trackers.FetchHistory(ctx, provider, symbol, period)
 
 	if err != nil {
 		slog.Error("Error fetching history", slog.Any("err", err))
@@ -198,3 +292,375 @@ func generateComponent(symbol, period string) (component discord.LayoutComponent
 	}
 	return
 }
+
+func fundamentalsHandler(ctx context.Context, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	symbol := yfa.NormalizeSymbol(args.Options["symbol"].String())
+
+	fundamentals, err := provider.Fundamentals(ctx, symbol)
+	if err != nil {
+		slog.Error("Error fetching fundamentals", slog.Any("err", err), slog.String("symbol", symbol))
+		response := util.SymbolErrorMessage(err, "error fetching the fundamentals")
+		_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content: &response,
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+		return
+	}
+
+	component := generateFundamentalsComponent(symbol, fundamentals)
+
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Components: &[]discord.LayoutComponent{component},
+		Flags:      util.ConfigFile.SetComponentV2Flags(),
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err), slog.Any(". With body:", component))
+	}
+}
+
+func generateFundamentalsComponent(symbol string, fundamentals yfa.Fundamentals) discord.LayoutComponent {
+	info := fundamentals.Price
+	summary := fundamentals.SummaryDetail
+	stats := fundamentals.DefaultKeyStatistics
+	financial := fundamentals.FinancialData
+
+	color := RED
+	if info.RegularMarketChangePercent != nil && info.RegularMarketChangePercent.Raw > 0 {
+		color = GREEN
+	}
+
+	header := fmt.Sprintf("# %s\n%s", symbol, info.LongName)
+	if profile := fundamentals.AssetProfile; profile.Sector != "" {
+		header += fmt.Sprintf("\n-# %s · %s", profile.Sector, profile.Industry)
+	}
+
+	earningsDate := "N/A"
+	if date, ok := fundamentals.NextEarningsDate(); ok {
+		earningsDate = fmt.Sprintf("<t:%d:D>", date.Unix())
+	}
+
+	target := fmtValue(financial.TargetMeanPrice)
+	if financial.RecommendationKey != "" && financial.RecommendationKey != "none" {
+		target += fmt.Sprintf(" (%s)", strings.ReplaceAll(financial.RecommendationKey, "_", " "))
+	}
+
+	return discord.ContainerComponent{
+		AccentColor: color,
+		Components: []discord.ContainerSubComponent{
+			discord.TextDisplayComponent{
+				Content: header,
+			},
+			discord.SeparatorComponent{
+				Divider: util.Pointer(true),
+			},
+			discord.TextDisplayComponent{
+				Content: fmt.Sprintf("**Valuation**\n**Market Cap:** %s\n**P/E:** %s\n**EPS (TTM):** %s\n**Forward EPS:** %s\n**Price/Book:** %s",
+					fmtValue(summary.MarketCap), fmtValue(fundamentals.PE()), fmtValue(stats.TrailingEps), fmtValue(stats.ForwardEps), fmtValue(stats.PriceToBook)),
+			},
+			discord.TextDisplayComponent{
+				Content: fmt.Sprintf("**Dividends**\n**Yield:** %s\n**Rate:** %s\n**Ex-Dividend Date:** %s\n**Payout Ratio:** %s",
+					fmtValue(summary.DividendYield), fmtValue(summary.DividendRate), fmtValue(summary.ExDividendDate), fmtValue(summary.PayoutRatio)),
+			},
+			discord.TextDisplayComponent{
+				Content: fmt.Sprintf("**Risk**\n**Beta:** %s\n**52 Week Range:** %s - %s",
+					fmtValue(fundamentals.Beta()), fmtValue(summary.FiftyTwoWeekLow), fmtValue(summary.FiftyTwoWeekHigh)),
+			},
+			discord.SeparatorComponent{
+				Divider: util.Pointer(true),
+			},
+			discord.TextDisplayComponent{
+				Content: fmt.Sprintf("**Earnings**\n**Next Earnings Date:** %s\n**EPS Estimate:** %s\n**Profit Margin:** %s\n**Analyst Target:** %s",
+					earningsDate, fmtValue(fundamentals.CalendarEvents.Earnings.EarningsAverage), fmtValue(financial.ProfitMargins), target),
+			},
+		},
+	}
+}
+
+// fmtValue returns the formatted value, or N/A when Yahoo has none
+func fmtValue(value *yfa.PriceValue) string {
+	if value == nil || value.Fmt == "" {
+		return "N/A"
+	}
+	return value.Fmt
+}
+
+func newsHandler(ctx context.Context, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	symbol := yfa.NormalizeSymbol(args.Options["symbol"].String())
+
+	news, err := provider.News(ctx, symbol)
+	if err != nil {
+		slog.Error("Error fetching news", slog.Any("err", err), slog.String("symbol", symbol))
+		response := "error fetching the news"
+		_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content: &response,
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+		return
+	}
+
+	component := generateNewsComponent(symbol, news)
+
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Components: &[]discord.LayoutComponent{component},
+		Flags:      util.ConfigFile.SetComponentV2Flags(),
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err), slog.Any(". With body:", component))
+	}
+}
+
+func generateNewsComponent(symbol string, news []yfa.NewsItem) discord.LayoutComponent {
+	components := []discord.ContainerSubComponent{
+		discord.TextDisplayComponent{
+			Content: fmt.Sprintf("# %s News", symbol),
+		},
+		discord.SeparatorComponent{
+			Divider: util.Pointer(true),
+		},
+	}
+
+	if len(news) == 0 {
+		components = append(components, discord.TextDisplayComponent{
+			Content: "No recent headlines",
+		})
+	}
+	for _, item := range news {
+		components = append(components, discord.TextDisplayComponent{
+			Content: fmt.Sprintf("%s\n-# %s", trackers.FormatHeadline(item), item.Publisher),
+		})
+	}
+
+	return discord.ContainerComponent{
+		Components: components,
+	}
+}
+
+func optionsHandler(ctx context.Context, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	symbol := yfa.NormalizeSymbol(args.Options["symbol"].String())
+	expiration := args.String("expiration")
+
+	component, file, err := generateOptionsComponent(ctx, symbol, expiration)
+	if err != nil {
+		slog.Error("Error generating the option chain", slog.Any("err", err), slog.String("symbol", symbol))
+		response := optionsErrorMessage(err)
+		_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content: &response,
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+		return
+	}
+
+	var files []*discord.File
+	if file != nil {
+		files = append(files, file)
+	}
+	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+		Components: &[]discord.LayoutComponent{component},
+		Files:      files,
+		Flags:      util.ConfigFile.SetComponentV2Flags(),
+	})
+	if err != nil {
+		slog.Error("Error editing the response:", slog.Any("err", err), slog.Any(". With body:", component))
+	}
+}
+
+// generateOptionsComponent renders the calls and puts near the money for the expiration, the nearest one if empty
+func generateOptionsComponent(ctx context.Context, symbol, expiration string) (component discord.LayoutComponent, file *discord.File, err error) {
+	info, err := provider.Info(ctx, symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	expirations, err := provider.ExpirationDates(ctx, symbol)
+	if err != nil {
+		return nil, nil, err
+	}
+	if expiration == "" {
+		expiration = expirations[0]
+	} else if !slices.Contains(expirations, expiration) {
+		return nil, nil, fmt.Errorf("%w for %s on %s", yfa.ErrNoOptions, symbol, expiration)
+	}
+
+	chain, err := provider.OptionChainByExpiration(ctx, symbol, expiration)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var price float64
+	if info.RegularMarketPrice != nil {
+		price = info.RegularMarketPrice.Raw
+	}
+
+	file = trackers.GenerateIVChart(symbol, chain, price)
+	calls, puts := nearTheMoney(chain.Calls, price), nearTheMoney(chain.Puts, price)
+
+	var color int
+	if info.RegularMarketChangePercent != nil && info.RegularMarketChangePercent.Raw > 0 {
+		color = GREEN
+	} else {
+		color = RED
+	}
+
+	components := []discord.ContainerSubComponent{
+		discord.TextDisplayComponent{
+			Content: fmt.Sprintf("# %s options\n**Expiration:** %s\n**Price:** %s%s %s", symbol, expiration, info.CurrencySymbol, fmtValue(info.RegularMarketPrice), info.Currency),
+		},
+		discord.SeparatorComponent{
+			Divider: util.Pointer(true),
+		},
+		discord.TextDisplayComponent{
+			Content: fmt.Sprintf("**Calls**\n%s\n%s", optionTable(calls), greeksTable(calls, true, price)),
+		},
+		discord.TextDisplayComponent{
+			Content: fmt.Sprintf("**Puts**\n%s\n%s", optionTable(puts), greeksTable(puts, false, price)),
+		},
+		discord.TextDisplayComponent{
+			Content: fmt.Sprintf("-# Black-Scholes values at a %.2f%% risk-free rate. Theta is per day, vega and rho per 1%%.", util.ConfigFile.RISK_FREE_RATE*100),
+		},
+	}
+
+	if file != nil {
+		components = append(components, discord.MediaGalleryComponent{
+			Items: []discord.MediaGalleryItem{
+				{
+					Media: discord.UnfurledMediaItem{
+						URL: fmt.Sprintf("attachment://%s", file.Name),
+					},
+				},
+			},
+		})
+	}
+
+	components = append(components,
+		discord.SeparatorComponent{
+			Divider: util.Pointer(true),
+		},
+		discord.ActionRowComponent{
+			Components: []discord.InteractiveComponent{
+				discord.StringSelectMenuComponent{
+					CustomID:    fmt.Sprintf("stock;%s;options", symbol),
+					Placeholder: "Expiration",
+					Options:     expirationOptions(expirations, expiration),
+				},
+			},
+		},
+	)
+
+	component = discord.ContainerComponent{
+		AccentColor: color,
+		Components:  components,
+	}
+	return
+}
+
+// optionsErrorMessage returns the user-facing message for an option chain error
+func optionsErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, yfa.ErrNoOptions):
+		return "There are no options listed for this symbol and expiration"
+	case errors.Is(err, yfa.ErrThrottled):
+		return "Yahoo Finance is throttling requests, try again in a minute"
+	default:
+		return util.SymbolErrorMessage(err, "error fetching the option chain")
+	}
+}
+
+// optionStrikesAroundPrice is the number of strikes shown on each side of the underlying price
+const optionStrikesAroundPrice = 5
+
+// nearTheMoney returns the contracts with the strikes closest to the price, sorted by strike
+func nearTheMoney(options []yfa.OptionDetail, price float64) []yfa.OptionDetail {
+	options = slices.Clone(options)
+	slices.SortFunc(options, func(a, b yfa.OptionDetail) int {
+		return cmp.Compare(a.Strike, b.Strike)
+	})
+
+	at, _ := slices.BinarySearchFunc(options, price, func(o yfa.OptionDetail, price float64) int {
+		return cmp.Compare(o.Strike, price)
+	})
+	return options[max(0, at-optionStrikesAroundPrice):min(len(options), at+optionStrikesAroundPrice)]
+}
+
+// optionTable formats the contracts as a monospaced table, in the money strikes are marked with a *
+func optionTable(options []yfa.OptionDetail) string {
+	if len(options) == 0 {
+		return "No contracts"
+	}
+
+	var table strings.Builder
+	table.WriteString("```\n")
+	fmt.Fprintf(&table, "%-10s %8s %8s %8s %7s\n", "Strike", "Bid", "Ask", "OI", "IV")
+	for _, option := range options {
+		strike := fmt.Sprintf("%.2f", option.Strike)
+		if option.InTheMoney {
+			strike += "*"
+		}
+		fmt.Fprintf(&table, "%-10s %8.2f %8.2f %8d %6.1f%%\n", strike, option.Bid, option.Ask, option.OpenInterest, option.ImpliedVolatility*100)
+	}
+	table.WriteString("```")
+	return table.String()
+}
+
+// greeksTable formats the Black-Scholes value and Greeks of the contracts as a monospaced table
+func greeksTable(options []yfa.OptionDetail, call bool, price float64) string {
+	if len(options) == 0 {
+		return ""
+	}
+
+	now := time.Now()
+	var table strings.Builder
+	table.WriteString("```\n")
+	fmt.Fprintf(&table, "%-10s %8s %6s %6s %6s %6s %6s\n", "Strike", "Value", "Delta", "Gamma", "Theta", "Vega", "Rho")
+	for _, option := range options {
+		greeks, ok := pricing.ForOption(option, call, price, util.ConfigFile.RISK_FREE_RATE, now)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&table, "%-10.2f %8.2f %6.2f %6.3f %6.2f %6.2f %6.2f\n", option.Strike, greeks.Value, greeks.Delta, greeks.Gamma, greeks.Theta, greeks.Vega, greeks.Rho)
+	}
+	table.WriteString("```")
+	return table.String()
+}
+
+// expirationOptions returns the select menu options for the expirations, Discord allows at most 25
+func expirationOptions(expirations []string, selected string) (options []discord.StringSelectMenuOption) {
+	for _, expiration := range expirations[:min(len(expirations), 25)] {
+		options = append(options, discord.StringSelectMenuOption{
+			Label:   expiration,
+			Value:   expiration,
+			Default: expiration == selected,
+		})
+	}
+	return
+}
+
+// expirationChoices returns the option expirations of the symbol starting with the typed prefix
+func expirationChoices(ctx context.Context, symbol, prefix string) []discord.AutocompleteChoice {
+	choices := []discord.AutocompleteChoice{}
+	if symbol == "" {
+		return choices
+	}
+
+	expirations, err := provider.ExpirationDates(ctx, symbol)
+	if err != nil {
+		return choices
+	}
+	for _, expiration := range expirations {
+		if len(choices) == 25 {
+			break
+		}
+		if strings.HasPrefix(expiration, prefix) {
+			choices = append(choices, discord.AutocompleteChoiceString{
+				Name:  expiration,
+				Value: expiration,
+			})
+		}
+	}
+	return choices
+}
@@ -1,14 +1,17 @@
 package watchcommand
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
-	"strings"
+	"time"
 
 	"github.com/disgoorg/disgo/discord"
 	"github.com/disgoorg/disgo/events"
 	"github.com/stollenaar/stockbot/internal/database"
 	"github.com/stollenaar/stockbot/internal/util"
+	"github.com/stollenaar/stockbot/internal/util/access"
+	"github.com/stollenaar/stockbot/internal/util/yfa"
 )
 
 var (
@@ -16,50 +19,88 @@ var (
 		Name:        "watch",
 		Description: "Watch interaction command",
 	}
+
+	provider yfa.MarketDataProvider = yfa.NewYahooProvider()
 )
 
+// SetProvider overrides the market data provider used by the watch command
+func SetProvider(p yfa.MarketDataProvider) {
+	provider = p
+}
+
 type WatchCommand struct {
 	Name        string
 	Description string
 }
 
-func (s WatchCommand) Handler(event *events.ApplicationCommandInteractionCreate) {
-	err := event.DeferCreateMessage(util.ConfigFile.SetEphemeral() == discord.MessageFlagEphemeral)
+func (s WatchCommand) Handler(ctx context.Context, event *events.ApplicationCommandInteractionCreate) {
+	sub := event.SlashCommandInteractionData()
+	shared := sub.Bool("guild")
+
+	// the shared watchlist is shown to the whole channel
+	err := event.DeferCreateMessage(!shared && util.ConfigFile.SetEphemeral() == discord.MessageFlagEphemeral)
 
 	if err != nil {
 		slog.Error("Error deferring: ", slog.Any("err", err))
 		return
 	}
 
-	sub := event.SlashCommandInteractionData()
+	owner, err := access.Resolve(event.User().ID, event.GuildID(), shared)
+	if err == nil && *sub.SubCommandName != "list" {
+		err = access.CheckEdit(ctx, owner, event.Member())
+	}
+	if err != nil {
+		response := access.ErrorMessage(err, "error checking the permissions")
+		_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
+			Content:         &response,
+			AllowedMentions: &discord.AllowedMentions{},
+		})
+		if err != nil {
+			slog.Error("Error editing the response:", slog.Any("err", err))
+		}
+		return
+	}
 
 	switch *sub.SubCommandName {
 	case "add":
-		addHandler(sub, event)
+		addHandler(ctx, owner, sub, event)
 	case "list":
-		listHandler(event)
+		listHandler(ctx, owner, event)
 	case "update":
-		addHandler(sub, event)
+		addHandler(ctx, owner, sub, event)
 	case "remove":
-		removeHandler(sub, event)
+		removeHandler(ctx, owner, sub, event)
 	}
 }
 
-func addHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+func addHandler(ctx context.Context, owner access.Owner, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
 	watchList := database.WatchList{
-		UserID:      event.User().ID.String(),
-		Symbol:      strings.ToUpper(args.Options["symbol"].String()),
+		UserID:      owner.ID,
+		Symbol:      yfa.NormalizeSymbol(args.Options["symbol"].String()),
 		PriceTarget: args.Options["price"].Float(),
 		Direction:   args.Options["above"].Bool(),
 	}
+	if owner.Guild {
+		// nobody gets a DM for a shared watch, the alerts go to the channel it was added from
+		watchList.ChannelID = event.Channel().ID().String()
+	}
+
+	if news, ok := args.OptBool("news"); ok {
+		if news {
+			watchList.NewsSince = util.Pointer(time.Now().UTC())
+		}
+	} else {
+		// leaving the option out keeps the news setting of an existing watch
+		watchList.NewsSince = existingNewsSince(ctx, watchList.UserID, watchList.Symbol)
+	}
 
-	err := watchList.UpsertWatchlist()
+	err := watchList.UpsertWatchlist(ctx)
 
 	response := "Successfully added the watched stock"
 
 	if err != nil {
 		slog.Error("Error adding the watchlist:", slog.Any("err", err))
-		response = "error adding the watched stock"
+		response = util.SymbolErrorMessage(err, "error adding the watched stock")
 	}
 
 	_, err = event.Client().Rest.UpdateInteractionResponse(event.ApplicationID(), event.Token(), discord.MessageUpdate{
@@ -70,8 +111,23 @@ func addHandler(args discord.SlashCommandInteractionData, event *events.Applicat
 	}
 }
 
-func listHandler(event *events.ApplicationCommandInteractionCreate) {
-	watches, err := database.GetUserWatchList(event.User().ID.String())
+// existingNewsSince returns when news was turned on for the watched stock, nil when it is off or not watched yet
+func existingNewsSince(ctx context.Context, userID, symbol string) *time.Time {
+	watches, err := database.GetUserWatchList(ctx, userID)
+	if err != nil {
+		slog.Error("Error fetching watchlists:", slog.Any("err", err))
+		return nil
+	}
+	for _, watch := range watches {
+		if watch.Symbol == symbol {
+			return watch.NewsSince
+		}
+	}
+	return nil
+}
+
+func listHandler(ctx context.Context, owner access.Owner, event *events.ApplicationCommandInteractionCreate) {
+	watches, err := database.GetUserWatchList(ctx, owner.ID)
 
 	if err != nil {
 		slog.Error("Error fetching watchlists:", slog.Any("err", err))
@@ -80,6 +136,9 @@ func listHandler(event *events.ApplicationCommandInteractionCreate) {
 	var embed discord.Embed
 
 	embed.Title = "Watched Stocks"
+	if owner.Guild {
+		embed.Title = "Server Watched Stocks"
+	}
 
 	if len(watches) == 0 {
 		embed.Description = "No watched stock yet"
@@ -94,9 +153,16 @@ func listHandler(event *events.ApplicationCommandInteractionCreate) {
 	}
 
 	for _, watch := range watches {
+		value := fmt.Sprintf("%.2f", watch.PriceTarget)
+		if watch.NewsSince != nil {
+			value += "\nnews on"
+		}
+		if watch.ChannelID != "" {
+			value += fmt.Sprintf("\nalerts in <#%s>", watch.ChannelID)
+		}
 		embed.Fields = append(embed.Fields, discord.EmbedField{
 			Name:  watch.Symbol,
-			Value: fmt.Sprintf("%.2f", watch.PriceTarget),
+			Value: value,
 		})
 	}
 
@@ -108,8 +174,8 @@ func listHandler(event *events.ApplicationCommandInteractionCreate) {
 	}
 }
 
-func removeHandler(args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
-	err := database.RemoveWatchList(event.User().ID.String(), strings.ToUpper(args.Options["symbol"].String()))
+func removeHandler(ctx context.Context, owner access.Owner, args discord.SlashCommandInteractionData, event *events.ApplicationCommandInteractionCreate) {
+	err := database.RemoveWatchList(ctx, owner.ID, yfa.NormalizeSymbol(args.Options["symbol"].String()))
 	response := "Successfully removed the watched stock"
 
 	if err != nil {
@@ -132,9 +198,10 @@ func (s WatchCommand) CreateCommandArguments() []discord.ApplicationCommandOptio
 			Description: "watch a stock",
 			Options: []discord.ApplicationCommandOption{
 				discord.ApplicationCommandOptionString{
-					Name:        "symbol",
-					Description: "stock symbol",
-					Required:    true,
+					Name:         "symbol",
+					Description:  "stock symbol",
+					Required:     true,
+					Autocomplete: true,
 				},
 				discord.ApplicationCommandOptionFloat{
 					Name:        "price",
@@ -146,20 +213,30 @@ func (s WatchCommand) CreateCommandArguments() []discord.ApplicationCommandOptio
 					Description: "if the price needs to be above the target",
 					Required:    true,
 				},
+				discord.ApplicationCommandOptionBool{
+					Name:        "news",
+					Description: "DM new headlines about the stock",
+					Required:    false,
+				},
+				guildOption,
 			},
 		},
 		discord.ApplicationCommandOptionSubCommand{
 			Name:        "list",
 			Description: "list watched stock",
+			Options: []discord.ApplicationCommandOption{
+				guildOption,
+			},
 		},
 		discord.ApplicationCommandOptionSubCommand{
 			Name:        "update",
 			Description: "update a watched stock",
 			Options: []discord.ApplicationCommandOption{
 				discord.ApplicationCommandOptionString{
-					Name:        "symbol",
-					Description: "stock symbol",
-					Required:    true,
+					Name:         "symbol",
+					Description:  "stock symbol",
+					Required:     true,
+					Autocomplete: true,
 				},
 				discord.ApplicationCommandOptionFloat{
 					Name:        "price",
@@ -171,6 +248,12 @@ func (s WatchCommand) CreateCommandArguments() []discord.ApplicationCommandOptio
 					Description: "if the price needs to be above the target",
 					Required:    true,
 				},
+				discord.ApplicationCommandOptionBool{
+					Name:        "news",
+					Description: "DM new headlines about the stock",
+					Required:    false,
+				},
+				guildOption,
 			},
 		},
 		discord.ApplicationCommandOptionSubCommand{
@@ -178,11 +261,32 @@ func (s WatchCommand) CreateCommandArguments() []discord.ApplicationCommandOptio
 			Description: "remove a watched stock",
 			Options: []discord.ApplicationCommandOption{
 				discord.ApplicationCommandOptionString{
-					Name:        "symbol",
-					Description: "stock symbol",
-					Required:    true,
+					Name:         "symbol",
+					Description:  "stock symbol",
+					Required:     true,
+					Autocomplete: true,
 				},
+				guildOption,
 			},
 		},
 	}
 }
+
+// guildOption switches a subcommand to the shared watchlist of the server
+var guildOption = discord.ApplicationCommandOptionBool{
+	Name:        "guild",
+	Description: "use the shared watchlist of this server, its alerts are posted in the channel",
+	Required:    false,
+}
+
+// AutocompleteHandler suggests the tickers matching the symbol being typed
+func (s WatchCommand) AutocompleteHandler(ctx context.Context, event *events.AutocompleteInteractionCreate) {
+	choices := []discord.AutocompleteChoice{}
+	if focused := event.Data.Focused(); focused.Name == "symbol" {
+		choices = util.SymbolChoices(ctx, provider, event.Data.String("symbol"))
+	}
+
+	if err := event.AutocompleteResult(choices); err != nil {
+		slog.Error("Error sending autocomplete result:", slog.Any("err", err))
+	}
+}
@@ -0,0 +1,201 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	// CashDeposit adds money to the portfolio
+	CashDeposit = "deposit"
+	// CashWithdrawal takes money out of the portfolio
+	CashWithdrawal = "withdrawal"
+	// CashDividend is a dividend received for a symbol
+	CashDividend = "dividend"
+)
+
+// CashEntry is a line of the cash ledger of a portfolio.
+// Amount is positive for money coming in, trades use the TransactionBuy and TransactionSell types.
+type CashEntry struct {
+	ID        int64
+	UserID    string
+	Portfolio string
+	Type      string
+	Date      time.Time
+	Amount    float64
+	Currency  string
+	// Symbol is set for trades and dividends
+	Symbol string
+}
+
+func (c CashEntry) Values() []interface{} {
+	return []interface{}{c.UserID, c.Portfolio, c.Type, c.Date, c.Amount, c.Currency, c.Symbol}
+}
+
+// AddCash records a deposit, withdrawal or dividend, the amount is given as a positive number.
+// A dividend without a currency is booked in the trading currency of its symbol.
+func AddCash(ctx context.Context, entry CashEntry) error {
+	switch entry.Type {
+	case CashDeposit, CashDividend:
+	case CashWithdrawal:
+		entry.Amount = -entry.Amount
+	default:
+		return fmt.Errorf("unknown cash entry type: %q", entry.Type)
+	}
+	if entry.Amount == 0 {
+		return fmt.Errorf("amount must be positive")
+	}
+
+	if entry.Currency == "" && entry.Symbol != "" {
+		currency, err := symbolCurrency(ctx, entry.Symbol)
+		if err != nil {
+			return err
+		}
+		entry.Currency, _ = MajorCurrency(currency)
+	}
+	entry.Currency = strings.ToUpper(strings.TrimSpace(entry.Currency))
+	if len(entry.Currency) != 3 {
+		return fmt.Errorf("invalid currency code: %q", entry.Currency)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkPortfolio(ctx, tx, entry.UserID, entry.Portfolio); err != nil {
+		return err
+	}
+	if err := insertCash(ctx, tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetCashBalances returns the cash of a portfolio per currency
+func GetCashBalances(ctx context.Context, userID, portfolio string) (map[string]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := duckdbClient.QueryContext(ctx, `
+		SELECT currency, SUM(amount)
+		FROM cash_ledger
+		WHERE user_id = ? AND portfolio = ?
+		GROUP BY currency
+		ORDER BY currency ASC;
+	`, userID, portfolio)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make(map[string]float64)
+	for rows.Next() {
+		var currency string
+		var amount float64
+		if err := rows.Scan(&currency, &amount); err != nil {
+			return nil, err
+		}
+		balances[currency] = amount
+	}
+	return balances, rows.Err()
+}
+
+// tradeCash returns the cash moved by a buy or sell, currency is the trading currency of the symbol
+func tradeCash(t Transaction, currency string) CashEntry {
+	major, divisor := MajorCurrency(currency)
+	amount := -(t.Quantity**t.Price + t.Fees)
+	if t.Type == TransactionSell {
+		amount = t.Quantity**t.Price - t.Fees
+	}
+	return CashEntry{
+		UserID:    t.UserID,
+		Portfolio: t.Portfolio,
+		Type:      t.Type,
+		Date:      t.Date,
+		Amount:    amount / divisor,
+		Currency:  major,
+		Symbol:    t.Symbol,
+	}
+}
+
+// symbolCurrency returns the currency the symbol trades in, like GBp for pence
+func symbolCurrency(ctx context.Context, symbol string) (string, error) {
+	infos, err := provider.Quotes(ctx, []string{symbol})
+	if err != nil {
+		return "", err
+	}
+	if currency := infos[symbol].Currency; currency != "" {
+		return currency, nil
+	}
+	return "", fmt.Errorf("no currency found for %s", symbol)
+}
+
+// backfillTradeCash books the cash of the trades queued by the cash backfill migration, so the balance
+// of portfolios with trades from before the cash ledger includes them too.
+// Trades of a symbol whose currency can't be looked up stay queued for the next start.
+func backfillTradeCash(ctx context.Context) error {
+	transactions, err := queryTransactions(ctx, duckdbClient, `WHERE id IN (SELECT transaction_id FROM cash_backfill)`)
+	if err != nil {
+		return err
+	}
+
+	currencies := make(map[string]string)
+	for _, t := range transactions {
+		currency, ok := currencies[t.Symbol]
+		if !ok {
+			if currency, err = symbolCurrency(ctx, t.Symbol); err != nil {
+				slog.Error("failed backfilling the cash of a trade", slog.Any("err", err), slog.String("symbol", t.Symbol))
+			}
+			currencies[t.Symbol] = currency
+		}
+		if currency == "" {
+			continue
+		}
+
+		if err := bookBackfill(ctx, t, currency); err != nil {
+			return err
+		}
+	}
+
+	// the trades of deleted portfolios are gone, so are their queue entries
+	queryCtx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	_, err = duckdbClient.ExecContext(queryCtx, `DELETE FROM cash_backfill WHERE transaction_id NOT IN (SELECT id FROM transactions);`)
+	return err
+}
+
+func bookBackfill(ctx context.Context, t Transaction, currency string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertCash(ctx, tx, tradeCash(t, currency)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM cash_backfill WHERE transaction_id = ?;`, t.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertCash(ctx context.Context, tx *sql.Tx, entry CashEntry) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO cash_ledger (user_id, portfolio, type, date, amount, currency, symbol)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''));
+	`, entry.Values()...)
+	return err
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBackfillTradeCash(t *testing.T) {
+	ctx := context.Background()
+	price := func(v float64) *float64 { return &v }
+	trade := func(kind string, days int, quantity, p float64) Transaction {
+		return Transaction{UserID: "backfill", Portfolio: DefaultPortfolio, Symbol: "ACME", Type: kind, Date: daysAgo(days), Quantity: quantity, Price: price(p)}
+	}
+
+	if err := AddTransaction(ctx, trade(TransactionBuy, 30, 10, 100)); err != nil {
+		t.Fatalf("AddTransaction: %v", err)
+	}
+
+	// trades from before the cash ledger, one of them on the date of the booked buy
+	tx, err := duckdbClient.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx: %v", err)
+	}
+	for _, earlier := range []Transaction{trade(TransactionBuy, 40, 5, 100), trade(TransactionSell, 35, 3, 110), trade(TransactionBuy, 30, 2, 100)} {
+		if err := insertTransaction(ctx, tx, earlier); err != nil {
+			t.Fatalf("insertTransaction: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	migration, err := changeLogFiles.ReadFile("changelog/11-cash-backfill.sql")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	for range 2 {
+		if _, err := duckdbClient.ExecContext(ctx, string(migration)); err != nil {
+			t.Fatalf("migration: %v", err)
+		}
+		var queued int
+		if err := duckdbClient.QueryRowContext(ctx, `SELECT COUNT(*) FROM cash_backfill;`).Scan(&queued); err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		if queued != 3 {
+			t.Fatalf("queued %d trades, want the 3 without cash", queued)
+		}
+	}
+
+	if err := backfillTradeCash(ctx); err != nil {
+		t.Fatalf("backfillTradeCash: %v", err)
+	}
+	balances, err := GetCashBalances(ctx, "backfill", DefaultPortfolio)
+	if err != nil {
+		t.Fatalf("GetCashBalances: %v", err)
+	}
+	// -1000 for the booked buy, -500 and -200 for the earlier buys and 330 for the sell
+	if len(balances) != 1 || !near(balances["USD"], -1370) {
+		t.Errorf("balances = %v, want -1370 USD", balances)
+	}
+
+	// once booked the trades are paired with their cash, so running the migration again queues nothing
+	if _, err := duckdbClient.ExecContext(ctx, string(migration)); err != nil {
+		t.Fatalf("migration: %v", err)
+	}
+	if err := backfillTradeCash(ctx); err != nil {
+		t.Fatalf("backfillTradeCash: %v", err)
+	}
+	if balances, _ := GetCashBalances(ctx, "backfill", DefaultPortfolio); !near(balances["USD"], -1370) {
+		t.Errorf("balances after a second backfill = %v, want -1370 USD", balances)
+	}
+}
@@ -0,0 +1,2 @@
+-- Yahoo null bars used to be decoded as zero prices
+DELETE FROM stock_prices WHERE close IS NULL OR close = 0;
@@ -0,0 +1,12 @@
+ALTER TABLE stock_prices ADD COLUMN adj_close DOUBLE;
+UPDATE stock_prices SET adj_close = close;
+
+CREATE TABLE IF NOT EXISTS corporate_actions (
+    symbol VARCHAR REFERENCES tracked_stocks(symbol),
+    date TIMESTAMP,
+    type VARCHAR,
+    amount DOUBLE,
+    numerator DOUBLE,
+    denominator DOUBLE,
+    PRIMARY KEY (symbol, date, type)
+);
@@ -0,0 +1,25 @@
+-- symbols used to be stored as typed, move lower-case symbols onto their canonical upper-case form
+INSERT INTO tracked_stocks (symbol)
+SELECT DISTINCT upper(symbol) FROM tracked_stocks WHERE symbol <> upper(symbol)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO stock_prices (symbol, date, open, high, low, close, adj_close, volume)
+SELECT upper(symbol), date, open, high, low, close, adj_close, volume FROM stock_prices WHERE symbol <> upper(symbol)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO corporate_actions (symbol, date, type, amount, numerator, denominator)
+SELECT upper(symbol), date, type, amount, numerator, denominator FROM corporate_actions WHERE symbol <> upper(symbol)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO portfolios (user_id, symbol, shares)
+SELECT user_id, upper(symbol), shares FROM portfolios WHERE symbol <> upper(symbol)
+ON CONFLICT DO UPDATE SET shares = shares + EXCLUDED.shares;
+
+INSERT INTO watchlists (user_id, symbol, price_target, direction, triggered)
+SELECT user_id, upper(symbol), price_target, direction, triggered FROM watchlists WHERE symbol <> upper(symbol)
+ON CONFLICT DO NOTHING;
+
+DELETE FROM stock_prices WHERE symbol <> upper(symbol);
+DELETE FROM corporate_actions WHERE symbol <> upper(symbol);
+DELETE FROM portfolios WHERE symbol <> upper(symbol);
+DELETE FROM watchlists WHERE symbol <> upper(symbol);
@@ -0,0 +1,2 @@
+-- DuckDB checks foreign keys against the rows deleted in the same transaction, so the parents go in their own migration
+DELETE FROM tracked_stocks WHERE symbol <> upper(symbol);
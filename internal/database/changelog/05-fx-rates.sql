@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS fx_rates (
+    base VARCHAR,
+    quote VARCHAR,
+    date TIMESTAMP,
+    rate DOUBLE,
+    fetched_at TIMESTAMP,
+    PRIMARY KEY (base, quote, date)
+);
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id VARCHAR PRIMARY KEY,
+    home_currency VARCHAR
+);
@@ -0,0 +1,10 @@
+-- news_since is set while the user gets new headlines for the watched symbol, older ones are never sent
+ALTER TABLE watchlists ADD COLUMN news_since TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS news_sent (
+    user_id VARCHAR,
+    news_id VARCHAR,
+    symbol VARCHAR,
+    sent_at TIMESTAMP,
+    PRIMARY KEY (user_id, news_id)
+);
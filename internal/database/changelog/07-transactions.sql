@@ -0,0 +1,18 @@
+CREATE SEQUENCE IF NOT EXISTS transactions_id_seq START 1;
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id BIGINT PRIMARY KEY DEFAULT nextval('transactions_id_seq'),
+    user_id VARCHAR,
+    symbol VARCHAR REFERENCES tracked_stocks(symbol),
+    type VARCHAR,
+    date TIMESTAMP,
+    quantity DOUBLE,
+    price DOUBLE,
+    fees DOUBLE DEFAULT 0
+);
+
+-- holdings from before transactions existed become an opening lot without a known cost
+INSERT INTO transactions (user_id, symbol, type, date, quantity, price, fees)
+SELECT user_id, symbol, 'adjust', date_trunc('day', current_timestamp::TIMESTAMP), shares, NULL, 0
+FROM portfolios
+WHERE shares <> 0;
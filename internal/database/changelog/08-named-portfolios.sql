@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS portfolio_headers (
+    user_id VARCHAR,
+    name VARCHAR,
+    created_at TIMESTAMP DEFAULT current_timestamp,
+    PRIMARY KEY (user_id, name)
+);
+
+-- every existing holding and trade moves into the user's default portfolio
+INSERT INTO portfolio_headers (user_id, name)
+SELECT user_id, 'default' FROM portfolios
+UNION
+SELECT user_id, 'default' FROM transactions;
+
+-- DuckDB can't change a primary key, so the holdings are copied into a table keyed by portfolio
+CREATE TABLE portfolio_holdings (
+    user_id VARCHAR,
+    portfolio VARCHAR DEFAULT 'default',
+    symbol VARCHAR REFERENCES tracked_stocks(symbol),
+    shares DOUBLE,
+    PRIMARY KEY (user_id, portfolio, symbol)
+);
+
+INSERT INTO portfolio_holdings (user_id, portfolio, symbol, shares)
+SELECT user_id, 'default', symbol, shares FROM portfolios;
+
+DROP TABLE portfolios;
+ALTER TABLE portfolio_holdings RENAME TO portfolios;
+
+ALTER TABLE transactions ADD COLUMN portfolio VARCHAR DEFAULT 'default';
@@ -0,0 +1,8 @@
+-- shared portfolios and watchlists use the guild id as their user_id, the role decides who may change them
+CREATE TABLE IF NOT EXISTS guild_settings (
+    guild_id VARCHAR PRIMARY KEY,
+    member_role VARCHAR
+);
+
+-- alerts of a guild watch are posted in the channel it was added from, NULL DMs the user
+ALTER TABLE watchlists ADD COLUMN channel_id VARCHAR;
@@ -0,0 +1,13 @@
+CREATE SEQUENCE IF NOT EXISTS cash_ledger_id_seq START 1;
+
+-- amounts are signed and in the major currency, buys and sells are recorded with their symbol
+CREATE TABLE IF NOT EXISTS cash_ledger (
+    id BIGINT PRIMARY KEY DEFAULT nextval('cash_ledger_id_seq'),
+    user_id VARCHAR,
+    portfolio VARCHAR,
+    type VARCHAR,
+    date TIMESTAMP,
+    amount DOUBLE,
+    currency VARCHAR,
+    symbol VARCHAR
+);
@@ -0,0 +1,25 @@
+-- trades recorded before the cash ledger existed are queued here, backfillTradeCash books their cash
+-- once the trading currency of the symbol is known, which only the market data provider can tell
+CREATE TABLE IF NOT EXISTS cash_backfill (
+    transaction_id BIGINT PRIMARY KEY
+);
+
+-- trades are paired with the ledger lines of the same holding, type and date, the unpaired ones are queued
+INSERT INTO cash_backfill (transaction_id)
+SELECT trades.id
+FROM (
+    SELECT id, user_id, portfolio, symbol, type, date,
+        row_number() OVER (PARTITION BY user_id, portfolio, symbol, type, date ORDER BY id) AS n
+    FROM transactions
+    WHERE type IN ('buy', 'sell')
+) AS trades
+LEFT JOIN (
+    SELECT user_id, portfolio, symbol, type, date,
+        row_number() OVER (PARTITION BY user_id, portfolio, symbol, type, date ORDER BY id) AS n
+    FROM cash_ledger
+    WHERE type IN ('buy', 'sell')
+) AS booked
+ON trades.user_id = booked.user_id AND trades.portfolio = booked.portfolio AND trades.symbol = booked.symbol
+    AND trades.type = booked.type AND trades.date = booked.date AND trades.n = booked.n
+WHERE booked.n IS NULL
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,2 @@
+-- a forward split multiplies the volume of the stored history, which overflows a 32-bit INTEGER on busy stocks
+ALTER TABLE stock_prices ALTER COLUMN volume SET DATA TYPE BIGINT;
@@ -0,0 +1,149 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+)
+
+type CorporateAction struct {
+	Symbol      string
+	Date        time.Time
+	Type        string
+	Amount      float64
+	Numerator   float64
+	Denominator float64
+}
+
+func (c CorporateAction) Values() []interface{} {
+	return []interface{}{c.Symbol, c.Date, c.Type, c.Amount, c.Numerator, c.Denominator}
+}
+
+// Ratio returns how many shares one share becomes after the split
+func (c CorporateAction) Ratio() float64 {
+	if c.Type != yfa.ActionSplit || c.Numerator == 0 || c.Denominator == 0 {
+		return 1
+	}
+	return c.Numerator / c.Denominator
+}
+
+// CorporateActionsFromYahoo converts the yfa actions of a symbol into rows, skipping undated ones
+func CorporateActionsFromYahoo(symbol string, actions []yfa.CorporateAction) (rows []CorporateAction) {
+	for _, action := range actions {
+		date, err := yfa.ParseDateKey(action.Date)
+		if err != nil {
+			continue
+		}
+		rows = append(rows, CorporateAction{
+			Symbol:      symbol,
+			Date:        date,
+			Type:        action.Type,
+			Amount:      action.Amount,
+			Numerator:   action.Numerator,
+			Denominator: action.Denominator,
+		})
+	}
+	return
+}
+
+// GetCorporateActions returns the dividends and splits of a symbol ordered by date ascending.
+func GetCorporateActions(ctx context.Context, symbol string) (actions []CorporateAction, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := duckdbClient.QueryContext(ctx, `
+		SELECT symbol, date, type, amount, numerator, denominator
+		FROM corporate_actions
+		WHERE symbol = ?
+		ORDER BY date ASC;
+	`, symbol)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var action CorporateAction
+		if err := rows.Scan(&action.Symbol, &action.Date, &action.Type, &action.Amount, &action.Numerator, &action.Denominator); err != nil {
+			return nil, err
+		}
+		actions = append(actions, action)
+	}
+	return actions, rows.Err()
+}
+
+// SetCorporateActions records the actions that aren't known yet.
+// With applySplits, every newly recorded split also rewrites the stored prices before its date
+// and the portfolio share counts and transactions, so all stay split adjusted like Yahoo's own history.
+func SetCorporateActions(ctx context.Context, actions []CorporateAction, applySplits bool) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, action := range actions {
+		var exists bool
+		err = tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM corporate_actions WHERE symbol = ? AND date = ? AND type = ?);`, action.Symbol, action.Date, action.Type).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO corporate_actions (symbol, date, type, amount, numerator, denominator)
+			VALUES (?, ?, ?, ?, ?, ?);
+		`, action.Values()...)
+		if err != nil {
+			return err
+		}
+
+		if !applySplits || action.Type != yfa.ActionSplit || action.Ratio() == 1 {
+			continue
+		}
+
+		ratio := action.Ratio()
+		_, err = tx.ExecContext(ctx, `
+			UPDATE stock_prices SET
+				open = open / ?,
+				high = high / ?,
+				low = low / ?,
+				close = close / ?,
+				adj_close = adj_close / ?,
+				volume = CAST(volume * ? AS BIGINT)
+			WHERE symbol = ? AND date < ?;
+		`, ratio, ratio, ratio, ratio, ratio, ratio, action.Symbol, action.Date)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE transactions SET quantity = quantity * ?, price = price / ? WHERE symbol = ? AND date < ?;`, ratio, ratio, action.Symbol, action.Date)
+		if err != nil {
+			return err
+		}
+
+		// trades from after the split are already in post-split shares, so the share count is summed
+		// from the rescaled transactions instead of multiplying the stored one
+		_, err = tx.ExecContext(ctx, `
+			UPDATE portfolios SET shares = held.shares
+			FROM (
+				SELECT user_id, portfolio, SUM(CASE WHEN type = ? THEN -quantity ELSE quantity END) AS shares
+				FROM transactions
+				WHERE symbol = ?
+				GROUP BY user_id, portfolio
+			) AS held
+			WHERE portfolios.symbol = ? AND portfolios.user_id = held.user_id AND portfolios.portfolio = held.portfolio;
+		`, TransactionSell, action.Symbol, action.Symbol)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+	"github.com/stollenaar/stockbot/internal/util/yfa/fakeyahoo"
+)
+
+// TestMain opens a database in a temporary directory and backfills tracked stocks from a fake Yahoo
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "stockbot-database")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fake := fakeyahoo.New()
+	for _, symbol := range []string{"ACME", "SPLT", "GAIN", "OLD", "BUSY"} {
+		fake.AddSymbol(symbol, symbol+" Corp", 100)
+	}
+	srv := fake.Start()
+	yfa.SetBaseURL(srv.URL)
+	yfa.ConfigureSharedClient(yfa.WithRateLimit(yfa.RateLimit{Rate: 1000, Burst: 1000}, nil))
+	SetProvider(yfa.NewYahooProvider())
+	if err := Open(dir); err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+	srv.Close()
+	Exit()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// daysAgo returns midnight UTC of the day, days before today
+func daysAgo(days int) time.Time {
+	return yfa.NormalizeDate(time.Now().UTC()).AddDate(0, 0, -days)
+}
+
+func TestSplitRecomputesShares(t *testing.T) {
+	ctx := context.Background()
+	price := func(v float64) *float64 { return &v }
+
+	trades := []Transaction{
+		{UserID: "split", Portfolio: DefaultPortfolio, Symbol: "SPLT", Type: TransactionBuy, Date: daysAgo(30), Quantity: 10, Price: price(100)},
+		{UserID: "split", Portfolio: DefaultPortfolio, Symbol: "SPLT", Type: TransactionSell, Date: daysAgo(20), Quantity: 4, Price: price(110)},
+		// bought after the split, in post-split shares
+		{UserID: "split", Portfolio: DefaultPortfolio, Symbol: "SPLT", Type: TransactionBuy, Date: daysAgo(5), Quantity: 3, Price: price(55)},
+	}
+	for _, trade := range trades {
+		if err := AddTransaction(ctx, trade); err != nil {
+			t.Fatalf("AddTransaction: %v", err)
+		}
+	}
+
+	split := CorporateAction{Symbol: "SPLT", Date: daysAgo(10), Type: yfa.ActionSplit, Numerator: 2, Denominator: 1}
+	if err := SetCorporateActions(ctx, []CorporateAction{split}, true); err != nil {
+		t.Fatalf("SetCorporateActions: %v", err)
+	}
+
+	// the 6 shares held before the split double, the 3 bought after stay
+	portfolio, err := GetPortfolio(ctx, "split", DefaultPortfolio, "SPLT")
+	if err != nil {
+		t.Fatalf("GetPortfolio: %v", err)
+	}
+	if portfolio.Shares != 15 {
+		t.Errorf("shares = %v, want 15", portfolio.Shares)
+	}
+
+	transactions, err := GetTransactions(ctx, "split", DefaultPortfolio, "SPLT")
+	if err != nil {
+		t.Fatalf("GetTransactions: %v", err)
+	}
+	want := []struct{ quantity, price float64 }{{20, 50}, {8, 55}, {3, 55}}
+	for i, tt := range want {
+		if got := transactions[i]; got.Quantity != tt.quantity || *got.Price != tt.price {
+			t.Errorf("transaction %d = %v at %v, want %v at %v", i, got.Quantity, *got.Price, tt.quantity, tt.price)
+		}
+	}
+
+	// a split that is already recorded isn't applied again
+	if err := SetCorporateActions(ctx, []CorporateAction{split}, true); err != nil {
+		t.Fatalf("SetCorporateActions: %v", err)
+	}
+	if portfolio, _ := GetPortfolio(ctx, "split", DefaultPortfolio, "SPLT"); portfolio.Shares != 15 {
+		t.Errorf("shares after recording the split again = %v, want 15", portfolio.Shares)
+	}
+}
+
+func TestSplitOfHighVolumeHistory(t *testing.T) {
+	ctx := context.Background()
+	if err := AddTrackedStock(ctx, "BUSY"); err != nil {
+		t.Fatalf("AddTrackedStock: %v", err)
+	}
+
+	date := time.Date(2015, 1, 2, 0, 0, 0, 0, time.UTC)
+	bar := StockPrice{Symbol: "BUSY", Date: date, Open: 500, High: 510, Low: 490, Close: 500, AdjClose: 500, Volume: 300_000_000}
+	if err := SetStockPrice(ctx, bar); err != nil {
+		t.Fatalf("SetStockPrice: %v", err)
+	}
+
+	// ten times the volume no longer fits a 32-bit integer
+	split := CorporateAction{Symbol: "BUSY", Date: date.AddDate(1, 0, 0), Type: yfa.ActionSplit, Numerator: 10, Denominator: 1}
+	if err := SetCorporateActions(ctx, []CorporateAction{split}, true); err != nil {
+		t.Fatalf("SetCorporateActions: %v", err)
+	}
+
+	prices, err := GetStockPrices(ctx, "BUSY", date, date)
+	if err != nil {
+		t.Fatalf("GetStockPrices: %v", err)
+	}
+	if len(prices) != 1 || prices[0].Volume != 3_000_000_000 || prices[0].Close != 50 {
+		t.Errorf("prices = %+v, want a close of 50 on a volume of 3000000000", prices)
+	}
+}
@@ -0,0 +1,186 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+)
+
+// DefaultHomeCurrency is used for users that haven't picked a home currency
+const DefaultHomeCurrency = "USD"
+
+// FXRateTTL is how long a stored rate is used before it is fetched again
+var FXRateTTL = 1 * time.Hour
+
+// minorUnits maps the currencies Yahoo quotes some exchanges in, like pence on the LSE, to their major currency
+var minorUnits = map[string]struct {
+	currency string
+	divisor  float64
+}{
+	"GBp": {"GBP", 100},
+	"GBX": {"GBP", 100},
+	"ILA": {"ILS", 100},
+	"ZAc": {"ZAR", 100},
+}
+
+// FXRate converts an amount in Base into Quote, Date is the market date of the rate
+type FXRate struct {
+	Base      string
+	Quote     string
+	Date      time.Time
+	Rate      float64
+	FetchedAt time.Time
+}
+
+func (r FXRate) Values() []interface{} {
+	return []interface{}{r.Base, r.Quote, r.Date, r.Rate, r.FetchedAt}
+}
+
+// Convert returns amount in the quote currency
+func (r FXRate) Convert(amount float64) float64 {
+	return amount * r.Rate
+}
+
+// MajorCurrency returns the major currency of a minor unit like GBp, and how many minor units make one
+func MajorCurrency(currency string) (string, float64) {
+	if minor, ok := minorUnits[currency]; ok {
+		return minor.currency, minor.divisor
+	}
+	return strings.ToUpper(currency), 1
+}
+
+// GetFXRate returns the rate from base to quote.
+// Rates are cached in fx_rates for FXRateTTL, when Yahoo can't be reached the last stored rate is returned.
+func GetFXRate(ctx context.Context, base, quote string) (FXRate, error) {
+	from, divisor := MajorCurrency(base)
+	to, _ := MajorCurrency(quote)
+	if from == "" || to == "" {
+		return FXRate{}, fmt.Errorf("missing currency converting %q to %q", base, quote)
+	}
+
+	if from == to {
+		now := time.Now()
+		return FXRate{Base: base, Quote: quote, Date: yfa.NormalizeDate(now), Rate: 1 / divisor, FetchedAt: now}, nil
+	}
+
+	stored, storedErr := getStoredFXRate(ctx, from, to)
+	if storedErr == nil && time.Since(stored.FetchedAt) < FXRateTTL {
+		return stored.minor(base, divisor), nil
+	}
+
+	fetched, err := fetchFXRate(ctx, from, to)
+	if err != nil {
+		if storedErr == nil {
+			slog.Error("failed fetching fx rate, using the stored one", slog.Any("err", err), slog.String("pair", from+to), slog.Time("date", stored.Date))
+			return stored.minor(base, divisor), nil
+		}
+		return FXRate{}, err
+	}
+
+	if err := setFXRate(ctx, fetched); err != nil {
+		slog.Error("failed storing fx rate", slog.Any("err", err), slog.String("pair", from+to))
+	}
+	return fetched.minor(base, divisor), nil
+}
+
+// minor scales a rate of the major currency to the minor unit it was asked for
+func (r FXRate) minor(base string, divisor float64) FXRate {
+	r.Base = base
+	r.Rate /= divisor
+	return r
+}
+
+// fetchFXRate gets the current rate of a pair through its Yahoo FX ticker, like EURUSD=X
+func fetchFXRate(ctx context.Context, base, quote string) (FXRate, error) {
+	info, err := provider.Info(ctx, fmt.Sprintf("%s%s=X", base, quote))
+	if err != nil {
+		return FXRate{}, err
+	}
+	if info.RegularMarketPrice == nil || info.RegularMarketPrice.Raw <= 0 {
+		return FXRate{}, fmt.Errorf("no fx rate found for %s/%s", base, quote)
+	}
+
+	now := time.Now()
+	date := now
+	if info.RegularMarketTime > 0 {
+		date = time.Unix(info.RegularMarketTime, 0)
+	}
+	return FXRate{
+		Base:      base,
+		Quote:     quote,
+		Date:      yfa.NormalizeDate(date.UTC()),
+		Rate:      info.RegularMarketPrice.Raw,
+		FetchedAt: now,
+	}, nil
+}
+
+func getStoredFXRate(ctx context.Context, base, quote string) (rate FXRate, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	row := duckdbClient.QueryRowContext(ctx, `
+		SELECT base, quote, date, rate, fetched_at
+		FROM fx_rates
+		WHERE base = ? AND quote = ?
+		ORDER BY date DESC
+		LIMIT 1;
+	`, base, quote)
+
+	err = row.Scan(&rate.Base, &rate.Quote, &rate.Date, &rate.Rate, &rate.FetchedAt)
+	return rate, err
+}
+
+func setFXRate(ctx context.Context, rate FXRate) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := duckdbClient.ExecContext(ctx, `
+		INSERT INTO fx_rates (base, quote, date, rate, fetched_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT DO UPDATE SET
+		rate = EXCLUDED.rate,
+		fetched_at = EXCLUDED.fetched_at;
+	`, rate.Values()...)
+	return err
+}
+
+// GetHomeCurrency returns the currency the user wants totals in, DefaultHomeCurrency when not set
+func GetHomeCurrency(ctx context.Context, userID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var currency string
+	err := duckdbClient.QueryRowContext(ctx, `SELECT home_currency FROM user_settings WHERE user_id = ?;`, userID).Scan(&currency)
+	if errors.Is(err, sql.ErrNoRows) || currency == "" {
+		return DefaultHomeCurrency, nil
+	}
+	return currency, err
+}
+
+// SetHomeCurrency stores the home currency of the user after checking Yahoo has a rate for it
+func SetHomeCurrency(ctx context.Context, userID, currency string) error {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 {
+		return fmt.Errorf("invalid currency code: %q", currency)
+	}
+	if _, err := GetFXRate(ctx, currency, DefaultHomeCurrency); err != nil {
+		return fmt.Errorf("unknown currency %s: %w", currency, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := duckdbClient.ExecContext(ctx, `
+		INSERT INTO user_settings (user_id, home_currency)
+		VALUES (?, ?)
+		ON CONFLICT DO UPDATE SET
+		home_currency = EXCLUDED.home_currency;
+	`, userID, currency)
+	return err
+}
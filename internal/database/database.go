@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"embed"
@@ -14,30 +15,37 @@ import (
 	"time"
 
 	_ "github.com/marcboeker/go-duckdb/v2" // DuckDB Go driver
-	"github.com/stollenaar/stockbot/internal/util"
 	"github.com/stollenaar/stockbot/internal/util/yfa"
 )
 
+// queryTimeout bounds a single database call
+const queryTimeout = 10 * time.Second
+
 var (
 	duckdbClient *sql.DB
+	provider     yfa.MarketDataProvider = yfa.NewYahooProvider()
 
 	//go:embed changelog/*.sql
 	changeLogFiles embed.FS
 )
 
+// SetProvider overrides the market data provider used to backfill tracked stocks
+func SetProvider(p yfa.MarketDataProvider) {
+	provider = p
+}
+
 func Exit() {
 	duckdbClient.Close()
 }
 
-func init() {
-
-	var err error
-
-	duckdbClient, err = sql.Open("duckdb", fmt.Sprintf("%s/stockbot.db", util.ConfigFile.DUCKDB_PATH))
-
+// Open opens the stockbot.db database in dir and applies the pending migrations.
+// It must be called during startup after SetProvider, before any other function of the package is used.
+func Open(dir string) error {
+	client, err := sql.Open("duckdb", fmt.Sprintf("%s/stockbot.db", dir))
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
+	duckdbClient = client
 
 	// Ensure changelog table exists
 	_, err = duckdbClient.Exec(`
@@ -51,14 +59,21 @@ func init() {
 	`)
 
 	if err != nil {
-		log.Fatalf("failed to create changelog table: %v", err)
+		return fmt.Errorf("failed to create changelog table: %w", err)
 	}
 
 	if err := runMigrations(); err != nil {
-		log.Fatalf("migration failed: %v", err)
+		return fmt.Errorf("migration failed: %w", err)
 	}
 
 	slog.Info("All migrations applied successfully.")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := backfillTradeCash(ctx); err != nil {
+		slog.Error("failed backfilling the cash of earlier trades", slog.Any("err", err))
+	}
+	return nil
 }
 
 func runMigrations() error {
@@ -76,6 +91,8 @@ func runMigrations() error {
 
 	sort.Strings(files)
 
+	var applied bool
+
 	for i, file := range files {
 		id := i + 1
 
@@ -126,6 +143,15 @@ func runMigrations() error {
 		}
 
 		log.Printf("Applied migration %s", file)
+		applied = true
+	}
+
+	// DuckDB can't replay an ALTER TABLE on a table with a nextval default from the WAL,
+	// so the migrations are written to the database file before anything else runs
+	if applied {
+		if _, err := duckdbClient.Exec(`CHECKPOINT;`); err != nil {
+			return fmt.Errorf("failed to checkpoint the migrations: %w", err)
+		}
 	}
 
 	return nil
@@ -133,12 +159,14 @@ func runMigrations() error {
 
 type Portfolio struct {
 	UserID string
+	// Name is the portfolio the holding belongs to
+	Name   string
 	Symbol string
 	Shares float64
 }
 
 func (p Portfolio) Values() []interface{} {
-	return []interface{}{p.UserID, p.Symbol, p.Shares}
+	return []interface{}{p.UserID, p.Name, p.Symbol, p.Shares}
 }
 
 type WatchList struct {
@@ -147,6 +175,10 @@ type WatchList struct {
 	PriceTarget float64
 	Triggered   bool
 	Direction   bool
+	// NewsSince is when the user started getting headlines for the symbol, nil when news is off
+	NewsSince *time.Time
+	// ChannelID is where the alerts of a guild watch are posted, empty DMs the user
+	ChannelID string
 }
 
 func (w WatchList) Values() []interface{} {
@@ -154,21 +186,25 @@ func (w WatchList) Values() []interface{} {
 }
 
 type StockPrice struct {
-	Symbol string
-	Date   time.Time
-	Open   float64
-	High   float64
-	Low    float64
-	Close  float64
-	Volume int64
+	Symbol   string
+	Date     time.Time
+	Open     float64
+	High     float64
+	Low      float64
+	Close    float64
+	AdjClose float64
+	Volume   int64
 }
 
 func (s StockPrice) Values() []interface{} {
-	return []interface{}{s.Symbol, s.Date, s.Open, s.High, s.Low, s.Close, s.Volume}
+	return []interface{}{s.Symbol, s.Date, s.Open, s.High, s.Low, s.Close, s.AdjClose, s.Volume}
 }
 
-func GetCompletePortfolio(userID string) (portfolio []Portfolio, err error) {
-	rows, err := duckdbClient.Query(`SELECT * FROM portfolios WHERE user_id = ?;`, userID)
+func GetCompletePortfolio(ctx context.Context, userID, name string) (portfolio []Portfolio, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := duckdbClient.QueryContext(ctx, `SELECT user_id, portfolio, symbol, shares FROM portfolios WHERE user_id = ? AND portfolio = ?;`, userID, name)
 	if err != nil {
 		return nil, err
 	}
@@ -176,7 +212,7 @@ func GetCompletePortfolio(userID string) (portfolio []Portfolio, err error) {
 	for rows.Next() {
 		var port Portfolio
 
-		err = rows.Scan(&port.UserID, &port.Symbol, &port.Shares)
+		err = rows.Scan(&port.UserID, &port.Name, &port.Symbol, &port.Shares)
 		if err != nil {
 			break
 		}
@@ -185,43 +221,99 @@ func GetCompletePortfolio(userID string) (portfolio []Portfolio, err error) {
 	return
 }
 
-func GetPortfolio(userID, symbol string) (portfolio Portfolio, err error) {
-	rows := duckdbClient.QueryRow(`SELECT * FROM portfolios WHERE user_id = ? AND symbol = ?;`, userID, symbol)
+func GetPortfolio(ctx context.Context, userID, name, symbol string) (portfolio Portfolio, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows := duckdbClient.QueryRowContext(ctx, `SELECT user_id, portfolio, symbol, shares FROM portfolios WHERE user_id = ? AND portfolio = ? AND symbol = ?;`, userID, name, symbol)
 
 	var port Portfolio
 
-	err = rows.Scan(&port.UserID, &port.Symbol, &port.Shares)
+	err = rows.Scan(&port.UserID, &port.Name, &port.Symbol, &port.Shares)
 
 	return port, err
 }
 
-func RemovePortfolio(userID, symbol string) error {
-	_, err := duckdbClient.Exec("DELETE FROM portfolios WHERE user_id = ? AND symbol = ?;", userID, symbol)
-	return err
+// RemovePortfolio closes the holding, the shares leave through an adjustment so the transaction history stays intact
+func RemovePortfolio(ctx context.Context, userID, name, symbol string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	shares, err := currentShares(ctx, tx, userID, name, symbol)
+	if err != nil {
+		return err
+	}
+	if shares != 0 {
+		if err := insertTransaction(ctx, tx, Transaction{
+			UserID:    userID,
+			Portfolio: name,
+			Symbol:    symbol,
+			Type:      TransactionAdjust,
+			Date:      yfa.NormalizeDate(time.Now().UTC()),
+			Quantity:  -shares,
+		}); err != nil {
+			return err
+		}
+	}
+	if err := setShares(ctx, tx, userID, name, symbol, 0); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (p *Portfolio) UpsertPortfolio() error {
-	tx, err := duckdbClient.Begin()
+// UpsertPortfolio sets the share count of the holding.
+// The difference is recorded as an adjustment, so the lots stay in line with the share count.
+// A missing portfolio returns ErrUnknownPortfolio.
+func (p *Portfolio) UpsertPortfolio(ctx context.Context) error {
+	if err := AddTrackedStock(ctx, p.Symbol); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
-	AddTrackedStock(p.Symbol)
 
-	_, err = tx.Exec(`
-		INSERT INTO portfolios (user_id, symbol, shares)
-		VALUES (?, ?, ?) 
-		ON CONFLICT DO UPDATE SET 
-		shares = EXCLUDED.shares;
-	`, p.UserID, p.Symbol, p.Shares)
+	if err := checkPortfolio(ctx, tx, p.UserID, p.Name); err != nil {
+		return err
+	}
+	shares, err := currentShares(ctx, tx, p.UserID, p.Name, p.Symbol)
 	if err != nil {
 		return err
 	}
+	if delta := p.Shares - shares; delta != 0 {
+		if err := insertTransaction(ctx, tx, Transaction{
+			UserID:    p.UserID,
+			Portfolio: p.Name,
+			Symbol:    p.Symbol,
+			Type:      TransactionAdjust,
+			Date:      yfa.NormalizeDate(time.Now().UTC()),
+			Quantity:  delta,
+		}); err != nil {
+			return err
+		}
+	}
+	if err := setShares(ctx, tx, p.UserID, p.Name, p.Symbol, p.Shares); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
-func GetUserWatchList(userID string) (watchlists []WatchList, err error) {
-	rows, err := duckdbClient.Query(`SELECT * FROM watchlists WHERE user_id = ?;`, userID)
+func GetUserWatchList(ctx context.Context, userID string) (watchlists []WatchList, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := duckdbClient.QueryContext(ctx, `SELECT user_id, symbol, price_target, direction, triggered, news_since, COALESCE(channel_id, '') FROM watchlists WHERE user_id = ?;`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -229,7 +321,7 @@ func GetUserWatchList(userID string) (watchlists []WatchList, err error) {
 	for rows.Next() {
 		var watchlist WatchList
 
-		err = rows.Scan(&watchlist.UserID, &watchlist.Symbol, &watchlist.PriceTarget, &watchlist.Direction, &watchlist.Triggered)
+		err = rows.Scan(&watchlist.UserID, &watchlist.Symbol, &watchlist.PriceTarget, &watchlist.Direction, &watchlist.Triggered, &watchlist.NewsSince, &watchlist.ChannelID)
 		if err != nil {
 			break
 		}
@@ -238,8 +330,11 @@ func GetUserWatchList(userID string) (watchlists []WatchList, err error) {
 	return
 }
 
-func GetWatchLists() (watchlists []WatchList, err error) {
-	rows, err := duckdbClient.Query(`SELECT * FROM watchlists WHERE triggered = false;`)
+func GetWatchLists(ctx context.Context) (watchlists []WatchList, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := duckdbClient.QueryContext(ctx, `SELECT user_id, symbol, price_target, direction, triggered, news_since, COALESCE(channel_id, '') FROM watchlists WHERE triggered = false;`)
 	if err != nil {
 		return nil, err
 	}
@@ -247,7 +342,7 @@ func GetWatchLists() (watchlists []WatchList, err error) {
 	for rows.Next() {
 		var watchlist WatchList
 
-		err = rows.Scan(&watchlist.UserID, &watchlist.Symbol, &watchlist.PriceTarget, &watchlist.Direction, &watchlist.Triggered)
+		err = rows.Scan(&watchlist.UserID, &watchlist.Symbol, &watchlist.PriceTarget, &watchlist.Direction, &watchlist.Triggered, &watchlist.NewsSince, &watchlist.ChannelID)
 		if err != nil {
 			break
 		}
@@ -256,40 +351,55 @@ func GetWatchLists() (watchlists []WatchList, err error) {
 	return
 }
 
-func RemoveWatchList(userID, symbol string) error {
-	_, err := duckdbClient.Exec("DELETE FROM watchlists WHERE user_id = ? AND symbol = ?;", userID, symbol)
+func RemoveWatchList(ctx context.Context, userID, symbol string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := duckdbClient.ExecContext(ctx, "DELETE FROM watchlists WHERE user_id = ? AND symbol = ?;", userID, symbol)
 	return err
 }
 
-func (w *WatchList) UpsertWatchlist() error {
-	tx, err := duckdbClient.Begin()
+func (w *WatchList) UpsertWatchlist(ctx context.Context) error {
+	if err := AddTrackedStock(ctx, w.Symbol); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
-	AddTrackedStock(w.Symbol)
 
-	_, err = tx.Exec(`
-		INSERT INTO watchlists (user_id, symbol, price_target, direction)
-		VALUES (?, ?, ?, ?) 
+	// keep the original news_since while news stays on, so headlines aren't sent again
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO watchlists (user_id, symbol, price_target, direction, news_since, channel_id)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, '')) 
 		ON CONFLICT DO UPDATE SET 
 		price_target = EXCLUDED.price_target,
-		direction = EXCLUDED.direction;
-	`, w.UserID, w.Symbol, w.PriceTarget, w.Direction)
+		direction = EXCLUDED.direction,
+		news_since = CASE WHEN EXCLUDED.news_since IS NULL THEN NULL ELSE COALESCE(news_since, EXCLUDED.news_since) END,
+		channel_id = EXCLUDED.channel_id;
+	`, w.UserID, w.Symbol, w.PriceTarget, w.Direction, w.NewsSince, w.ChannelID)
 	if err != nil {
 		return err
 	}
 	return tx.Commit()
 }
 
-func (w *WatchList) SetTriggerWatchlist() error {
-	tx, err := duckdbClient.Begin()
+func (w *WatchList) SetTriggerWatchlist(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(ctx, `
 		UPDATE watchlists 
 		SET triggered = true
 		WHERE user_id = ? AND symbol = ?;
@@ -301,17 +411,23 @@ func (w *WatchList) SetTriggerWatchlist() error {
 }
 
 // IsTrackedStock checks whether the given symbol exists in tracked_stocks.
-func IsTrackedStock(symbol string) (bool, error) {
+func IsTrackedStock(ctx context.Context, symbol string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
 	var existsInt int
-	row := duckdbClient.QueryRow(`SELECT EXISTS(SELECT 1 FROM tracked_stocks WHERE symbol = ?);`, symbol)
+	row := duckdbClient.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM tracked_stocks WHERE symbol = ?);`, symbol)
 	if err := row.Scan(&existsInt); err != nil {
 		return false, err
 	}
 	return existsInt == 1, nil
 }
 
-func GetTrackedStocks() (tracked []string, err error) {
-	rows, err := duckdbClient.Query(`SELECT * FROM tracked_stocks;`)
+func GetTrackedStocks(ctx context.Context) (tracked []string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := duckdbClient.QueryContext(ctx, `SELECT * FROM tracked_stocks;`)
 
 	if err != nil {
 		return nil, err
@@ -329,61 +445,92 @@ func GetTrackedStocks() (tracked []string, err error) {
 }
 
 // AddTrackedStock inserts the symbol into tracked_stocks (no-op if already present).
-func AddTrackedStock(symbol string) error {
-	if ok, _ := IsTrackedStock(symbol); !ok {
-		_, err := duckdbClient.Exec(`INSERT INTO tracked_stocks (symbol) VALUES (?) ON CONFLICT DO NOTHING;`, symbol)
+// New symbols are validated against Yahoo first, an unknown symbol returns a yfa.UnknownSymbolError.
+func AddTrackedStock(ctx context.Context, symbol string) error {
+	if ok, _ := IsTrackedStock(ctx, symbol); !ok {
+		if err := yfa.ValidateSymbol(ctx, provider, symbol); err != nil {
+			return err
+		}
+
+		queryCtx, cancel := context.WithTimeout(ctx, queryTimeout)
+		_, err := duckdbClient.ExecContext(queryCtx, `INSERT INTO tracked_stocks (symbol) VALUES (?) ON CONFLICT DO NOTHING;`, symbol)
+		cancel()
 
 		if err != nil {
 			return err
 		}
 
-		ticker := yfa.NewTicker(symbol)
-		hist, err := yfa.FetchHistory(ticker)
+		// one chart request returns the backfill and its dividends and splits
+		chart, err := yfa.FetchChart(ctx, provider, symbol)
 
 		if err != nil {
 			slog.Error("failed getting 5year history", slog.Any("err", err))
+			// untrack it again so the next add retries the backfill
+			if err := RemoveTrackedStock(ctx, symbol); err != nil {
+				slog.Error("failed untracking stock", slog.Any("err", err), slog.String("symbol", symbol))
+			}
 			return err
 		}
 
 		var stockPrices []StockPrice
 
-		for date, price := range hist {
+		for date, price := range chart.History {
+			if !price.Valid() {
+				continue
+			}
 			// parse the date key into a time.Time (stored as UTC)
-			parsedDate, _ := time.ParseInLocation("2006-01-02", date, time.UTC)
+			parsedDate, err := yfa.ParseDateKey(date)
+			if err != nil {
+				continue
+			}
 
 			// build StockPrice and persist
 			stockPrices = append(stockPrices, StockPrice{
-				Symbol: symbol,
-				Date:   parsedDate,
-				Open:   price.Open,
-				High:   price.High,
-				Low:    price.Low,
-				Close:  price.Close,
-				Volume: price.Volume,
+				Symbol:   symbol,
+				Date:     parsedDate,
+				Open:     price.Open,
+				High:     price.High,
+				Low:      price.Low,
+				Close:    price.Close,
+				AdjClose: price.AdjClose,
+				Volume:   price.Volume,
 			})
 		}
 
-		if err := SetStockPrices(stockPrices); err != nil {
+		if err := SetStockPrices(ctx, stockPrices); err != nil {
 			slog.Error("failed to set stock price", slog.Any("err", err), slog.String("symbol", symbol))
 		}
+
+		// the backfilled prices are already split adjusted, so the actions are only recorded
+		if err := SetCorporateActions(ctx, CorporateActionsFromYahoo(symbol, chart.Actions), false); err != nil {
+			slog.Error("failed to set corporate actions", slog.Any("err", err), slog.String("symbol", symbol))
+		}
 	}
 	return nil
 }
 
 // RemoveTrackedStock deletes the symbol from tracked_stocks and optionally its prices.
-func RemoveTrackedStock(symbol string) error {
-	tx, err := duckdbClient.Begin()
+func RemoveTrackedStock(ctx context.Context, symbol string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(`DELETE FROM stock_prices WHERE symbol = ?;`, symbol)
+	_, err = tx.ExecContext(ctx, `DELETE FROM stock_prices WHERE symbol = ?;`, symbol)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM corporate_actions WHERE symbol = ?;`, symbol)
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(`DELETE FROM tracked_stocks WHERE symbol = ?;`, symbol)
+	_, err = tx.ExecContext(ctx, `DELETE FROM tracked_stocks WHERE symbol = ?;`, symbol)
 	if err != nil {
 		return err
 	}
@@ -393,12 +540,15 @@ func RemoveTrackedStock(symbol string) error {
 
 // GetStockPrices returns stock prices for a symbol between start and end (inclusive),
 // ordered by date ascending.
-func GetStockPrices(symbol string, start, end time.Time) (prices []StockPrice, err error) {
-	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
-	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+func GetStockPrices(ctx context.Context, symbol string, start, end time.Time) (prices []StockPrice, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
 
-	rows, err := duckdbClient.Query(`
-        SELECT symbol, date, open, high, low, close, volume
+	start = yfa.NormalizeDate(start)
+	end = yfa.NormalizeDate(end)
+
+	rows, err := duckdbClient.QueryContext(ctx, `
+        SELECT symbol, date, open, high, low, close, COALESCE(adj_close, close), volume
         FROM stock_prices
         WHERE symbol = ? AND date >= ? AND date <= ?
         ORDER BY date ASC;
@@ -410,7 +560,7 @@ func GetStockPrices(symbol string, start, end time.Time) (prices []StockPrice, e
 
 	for rows.Next() {
 		var sp StockPrice
-		if err := rows.Scan(&sp.Symbol, &sp.Date, &sp.Open, &sp.High, &sp.Low, &sp.Close, &sp.Volume); err != nil {
+		if err := rows.Scan(&sp.Symbol, &sp.Date, &sp.Open, &sp.High, &sp.Low, &sp.Close, &sp.AdjClose, &sp.Volume); err != nil {
 			slog.Error("failed scanning stock price row", slog.Any("err", err))
 			return nil, err
 		}
@@ -422,25 +572,42 @@ func GetStockPrices(symbol string, start, end time.Time) (prices []StockPrice, e
 	return prices, nil
 }
 
+// GetLatestStockPriceDate returns the date of the most recent stored price of a symbol, false when there is none.
+func GetLatestStockPriceDate(ctx context.Context, symbol string) (time.Time, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var latest sql.NullTime
+	err := duckdbClient.QueryRowContext(ctx, `SELECT MAX(date) FROM stock_prices WHERE symbol = ?;`, symbol).Scan(&latest)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return latest.Time, latest.Valid, nil
+}
+
 // SetStockPrice inserts or updates a price row for the given symbol/date.
 // volume can be 0 if unknown.
-func SetStockPrice(stock StockPrice) error {
-	tx, err := duckdbClient.Begin()
+func SetStockPrice(ctx context.Context, stock StockPrice) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	_, err = tx.Exec(`
-        INSERT INTO stock_prices (symbol, date, open, high, low, close, volume)
-        VALUES (?, ?, ?, ?, ?, ?, ?)
+	_, err = tx.ExecContext(ctx, `
+        INSERT INTO stock_prices (symbol, date, open, high, low, close, adj_close, volume)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
         ON CONFLICT DO UPDATE SET
             open = EXCLUDED.open,
             high = EXCLUDED.high,
             low = EXCLUDED.low,
             close = EXCLUDED.close,
+            adj_close = EXCLUDED.adj_close,
             volume = EXCLUDED.volume;
-    `, stock.Symbol, stock.Date, stock.Open, stock.High, stock.Low, stock.Close, stock.Volume)
+    `, stock.Symbol, stock.Date, stock.Open, stock.High, stock.Low, stock.Close, stock.AdjClose, stock.Volume)
 	if err != nil {
 		return err
 	}
@@ -450,24 +617,28 @@ func SetStockPrice(stock StockPrice) error {
 
 // SetStockPrices inserts or updates a price row for the given symbol/date.
 // volume can be 0 if unknown.
-func SetStockPrices(stocks []StockPrice) error {
-	tx, err := duckdbClient.Begin()
+func SetStockPrices(ctx context.Context, stocks []StockPrice) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
 	for _, stock := range stocks {
-		_, err = tx.Exec(`
-        INSERT INTO stock_prices (symbol, date, open, high, low, close, volume)
-        VALUES (?, ?, ?, ?, ?, ?, ?)
+		_, err = tx.ExecContext(ctx, `
+        INSERT INTO stock_prices (symbol, date, open, high, low, close, adj_close, volume)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
         ON CONFLICT DO UPDATE SET
             open = EXCLUDED.open,
             high = EXCLUDED.high,
             low = EXCLUDED.low,
             close = EXCLUDED.close,
+            adj_close = EXCLUDED.adj_close,
             volume = EXCLUDED.volume;
-    `, stock.Symbol, stock.Date, stock.Open, stock.High, stock.Low, stock.Close, stock.Volume)
+    `, stock.Symbol, stock.Date, stock.Open, stock.High, stock.Low, stock.Close, stock.AdjClose, stock.Volume)
 		if err != nil {
 			slog.Error("failed committing stock price", slog.Any("err", err))
 		}
@@ -477,7 +648,10 @@ func SetStockPrices(stocks []StockPrice) error {
 }
 
 // RemoveStockPrice deletes a single price row for the given symbol and date.
-func RemoveStockPrice(symbol string, date time.Time) error {
-	_, err := duckdbClient.Exec(`DELETE FROM stock_prices WHERE symbol = ? AND date = ?;`, symbol, date)
+func RemoveStockPrice(ctx context.Context, symbol string, date time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := duckdbClient.ExecContext(ctx, `DELETE FROM stock_prices WHERE symbol = ? AND date = ?;`, symbol, date)
 	return err
 }
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// GetRealizedGains returns the gains realized by the sells of a portfolio in the tax year, oldest first.
+// Sells are matched against every earlier transaction, so lots bought in previous years are included.
+func GetRealizedGains(ctx context.Context, userID, portfolio string, year int, method CostMethod) ([]RealizedGain, error) {
+	end := time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	transactions, err := queryTransactions(ctx, duckdbClient, `WHERE user_id = ? AND portfolio = ? AND date < ?`, userID, portfolio, end)
+	if err != nil {
+		return nil, err
+	}
+
+	bySymbol := make(map[string][]Transaction)
+	var symbols []string
+	for _, t := range transactions {
+		if _, ok := bySymbol[t.Symbol]; !ok {
+			symbols = append(symbols, t.Symbol)
+		}
+		bySymbol[t.Symbol] = append(bySymbol[t.Symbol], t)
+	}
+
+	var gains []RealizedGain
+	for _, symbol := range symbols {
+		for _, gain := range BuildHolding(symbol, bySymbol[symbol], method).Realized {
+			if gain.Sold.Year() == year {
+				gains = append(gains, gain)
+			}
+		}
+	}
+	slices.SortStableFunc(gains, func(a, b RealizedGain) int {
+		return a.Sold.Compare(b.Sold)
+	})
+	return gains, nil
+}
+
+// RealizedGainsCSV renders the gains as a CSV with one row per matched lot, currencies are looked up per symbol
+func RealizedGainsCSV(gains []RealizedGain, currencies map[string]string) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	writer.Write([]string{"symbol", "acquired", "sold", "quantity", "proceeds", "cost", "gain", "term", "currency"})
+	for _, gain := range gains {
+		cost, result := "", ""
+		if gain.CostKnown {
+			cost = strconv.FormatFloat(gain.Cost, 'f', 2, 64)
+			result = strconv.FormatFloat(gain.Gain(), 'f', 2, 64)
+		}
+		term := "short"
+		if gain.LongTerm() {
+			term = "long"
+		}
+		writer.Write([]string{
+			gain.Symbol,
+			gain.Acquired.Format("2006-01-02"),
+			gain.Sold.Format("2006-01-02"),
+			strconv.FormatFloat(gain.Quantity, 'f', -1, 64),
+			strconv.FormatFloat(gain.Proceeds, 'f', 2, 64),
+			cost,
+			result,
+			term,
+			currencies[gain.Symbol],
+		})
+	}
+
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetRealizedGains(t *testing.T) {
+	ctx := context.Background()
+	date := func(value string) time.Time {
+		d, _ := time.Parse("2006-01-02", value)
+		return d
+	}
+	price := func(v float64) *float64 { return &v }
+
+	if err := CreatePortfolio(ctx, "gains", "other"); err != nil {
+		t.Fatalf("CreatePortfolio: %v", err)
+	}
+	trades := []Transaction{
+		{Portfolio: DefaultPortfolio, Symbol: "GAIN", Type: TransactionBuy, Date: date("2023-03-01"), Quantity: 10, Price: price(50)},
+		{Portfolio: DefaultPortfolio, Symbol: "GAIN", Type: TransactionSell, Date: date("2023-06-01"), Quantity: 4, Price: price(60)},
+		{Portfolio: DefaultPortfolio, Symbol: "GAIN", Type: TransactionBuy, Date: date("2024-02-01"), Quantity: 5, Price: price(70)},
+		{Portfolio: DefaultPortfolio, Symbol: "GAIN", Type: TransactionSell, Date: date("2024-03-02"), Quantity: 8, Price: price(80), Fees: 8},
+		{Portfolio: DefaultPortfolio, Symbol: "OLD", Type: TransactionBuy, Date: date("2022-05-10"), Quantity: 2, Price: price(100)},
+		{Portfolio: DefaultPortfolio, Symbol: "OLD", Type: TransactionSell, Date: date("2024-01-15"), Quantity: 2, Price: price(90)},
+		{Portfolio: DefaultPortfolio, Symbol: "GAIN", Type: TransactionSell, Date: date("2025-01-10"), Quantity: 1, Price: price(90)},
+		{Portfolio: "other", Symbol: "GAIN", Type: TransactionBuy, Date: date("2024-01-02"), Quantity: 1, Price: price(65)},
+		{Portfolio: "other", Symbol: "GAIN", Type: TransactionSell, Date: date("2024-01-03"), Quantity: 1, Price: price(66)},
+	}
+	for _, trade := range trades {
+		trade.UserID = "gains"
+		if err := AddTransaction(ctx, trade); err != nil {
+			t.Fatalf("AddTransaction: %v", err)
+		}
+	}
+
+	tests := []struct {
+		year int
+		want []RealizedGain
+	}{
+		{year: 2022},
+		{year: 2023, want: []RealizedGain{
+			{Symbol: "GAIN", Acquired: date("2023-03-01"), Sold: date("2023-06-01"), Quantity: 4, Proceeds: 240, Cost: 200, CostKnown: true},
+		}},
+		// the 2024 sells are matched against the lots left from 2022 and 2023 first
+		{year: 2024, want: []RealizedGain{
+			{Symbol: "OLD", Acquired: date("2022-05-10"), Sold: date("2024-01-15"), Quantity: 2, Proceeds: 180, Cost: 200, CostKnown: true},
+			{Symbol: "GAIN", Acquired: date("2023-03-01"), Sold: date("2024-03-02"), Quantity: 6, Proceeds: 474, Cost: 300, CostKnown: true},
+			{Symbol: "GAIN", Acquired: date("2024-02-01"), Sold: date("2024-03-02"), Quantity: 2, Proceeds: 158, Cost: 140, CostKnown: true},
+		}},
+		{year: 2025, want: []RealizedGain{
+			{Symbol: "GAIN", Acquired: date("2024-02-01"), Sold: date("2025-01-10"), Quantity: 1, Proceeds: 90, Cost: 70, CostKnown: true},
+		}},
+	}
+
+	for _, tt := range tests {
+		gains, err := GetRealizedGains(ctx, "gains", DefaultPortfolio, tt.year, CostFIFO)
+		if err != nil {
+			t.Fatalf("GetRealizedGains(%d): %v", tt.year, err)
+		}
+		if len(gains) != len(tt.want) {
+			t.Errorf("GetRealizedGains(%d) = %+v, want %+v", tt.year, gains, tt.want)
+			continue
+		}
+		for i, want := range tt.want {
+			got := gains[i]
+			if got.Symbol != want.Symbol || !got.Acquired.Equal(want.Acquired) || !got.Sold.Equal(want.Sold) ||
+				!near(got.Quantity, want.Quantity) || !near(got.Proceeds, want.Proceeds) || !near(got.Cost, want.Cost) || got.CostKnown != want.CostKnown {
+				t.Errorf("GetRealizedGains(%d)[%d] = %+v, want %+v", tt.year, i, got, want)
+			}
+		}
+	}
+}
+
+func TestRealizedGainLongTerm(t *testing.T) {
+	date := func(value string) time.Time {
+		d, _ := time.Parse("2006-01-02", value)
+		return d
+	}
+
+	tests := []struct {
+		acquired, sold string
+		want           bool
+	}{
+		{acquired: "2023-03-01", sold: "2023-12-31", want: false},
+		{acquired: "2023-03-01", sold: "2024-03-01", want: false},
+		{acquired: "2023-03-01", sold: "2024-03-02", want: true},
+		{acquired: "2020-01-15", sold: "2024-01-15", want: true},
+		// a year after a leap day runs to the first of March
+		{acquired: "2024-02-29", sold: "2025-03-01", want: false},
+		{acquired: "2024-02-29", sold: "2025-03-02", want: true},
+	}
+	for _, tt := range tests {
+		gain := RealizedGain{Acquired: date(tt.acquired), Sold: date(tt.sold)}
+		if got := gain.LongTerm(); got != tt.want {
+			t.Errorf("LongTerm(%s to %s) = %v, want %v", tt.acquired, tt.sold, got, tt.want)
+		}
+	}
+}
+
+func TestRealizedGainsCSV(t *testing.T) {
+	date := func(value string) time.Time {
+		d, _ := time.Parse("2006-01-02", value)
+		return d
+	}
+	gains := []RealizedGain{
+		{Symbol: "GAIN", Acquired: date("2023-03-01"), Sold: date("2024-03-02"), Quantity: 6, Proceeds: 474, Cost: 300, CostKnown: true},
+		{Symbol: "VOD.L", Acquired: date("2024-01-02"), Sold: date("2024-03-02"), Quantity: 2.5, Proceeds: 1.234},
+		{Symbol: "OLD", Acquired: date("2022-05-10"), Sold: date("2024-01-15"), Quantity: 2, Proceeds: 180, Cost: 200, CostKnown: true},
+	}
+
+	csv, err := RealizedGainsCSV(gains, map[string]string{"GAIN": "USD", "VOD.L": "GBP"})
+	if err != nil {
+		t.Fatalf("RealizedGainsCSV: %v", err)
+	}
+	want := "symbol,acquired,sold,quantity,proceeds,cost,gain,term,currency\n" +
+		"GAIN,2023-03-01,2024-03-02,6,474.00,300.00,174.00,long,USD\n" +
+		"VOD.L,2024-01-02,2024-03-02,2.5,1.23,,,short,GBP\n" +
+		"OLD,2022-05-10,2024-01-15,2,180.00,200.00,-20.00,long,\n"
+	if string(csv) != want {
+		t.Errorf("RealizedGainsCSV =\n%s\nwant\n%s", csv, want)
+	}
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// GetGuildMemberRole returns the role allowed to change the shared portfolios and watchlists of the guild, empty when none is set
+func GetGuildMemberRole(ctx context.Context, guildID string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var role sql.NullString
+	err := duckdbClient.QueryRowContext(ctx, `SELECT member_role FROM guild_settings WHERE guild_id = ?;`, guildID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role.String, err
+}
+
+// SetGuildMemberRole stores the role allowed to change the shared data of the guild
+func SetGuildMemberRole(ctx context.Context, guildID, roleID string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := duckdbClient.ExecContext(ctx, `
+		INSERT INTO guild_settings (guild_id, member_role)
+		VALUES (?, ?)
+		ON CONFLICT DO UPDATE SET
+		member_role = EXCLUDED.member_role;
+	`, guildID, roleID)
+	return err
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// CostMethod selects which lots a sell is matched against
+type CostMethod string
+
+const (
+	// CostFIFO sells the oldest lots first
+	CostFIFO CostMethod = "fifo"
+	// CostAverage sells from every lot in proportion, so each share costs the average price
+	CostAverage CostMethod = "average"
+)
+
+// ParseCostMethod returns the method for a command option, FIFO when empty
+func ParseCostMethod(method string) (CostMethod, error) {
+	switch CostMethod(method) {
+	case "", CostFIFO:
+		return CostFIFO, nil
+	case CostAverage:
+		return CostAverage, nil
+	}
+	return "", fmt.Errorf("unknown cost method: %q", method)
+}
+
+// Lot is a block of shares acquired on one date, Cost includes the fees of the buy
+type Lot struct {
+	Acquired  time.Time
+	Quantity  float64
+	Cost      float64
+	CostKnown bool
+}
+
+// RealizedGain is the part of a sell matched against a single lot
+type RealizedGain struct {
+	Symbol    string
+	Acquired  time.Time
+	Sold      time.Time
+	Quantity  float64
+	Proceeds  float64
+	Cost      float64
+	CostKnown bool
+}
+
+// Gain returns the proceeds minus the cost, only meaningful when CostKnown
+func (r RealizedGain) Gain() float64 {
+	return r.Proceeds - r.Cost
+}
+
+// LongTerm reports whether the shares were held for more than a year
+func (r RealizedGain) LongTerm() bool {
+	return r.Sold.After(r.Acquired.AddDate(1, 0, 0))
+}
+
+// Holding is the open lots and realized gains of a symbol, derived from its transactions
+type Holding struct {
+	Symbol   string
+	Lots     []Lot
+	Realized []RealizedGain
+}
+
+// BuildHolding replays the transactions of one symbol, oldest first, into lots using method
+func BuildHolding(symbol string, transactions []Transaction, method CostMethod) Holding {
+	holding := Holding{Symbol: symbol}
+	for _, t := range transactions {
+		switch {
+		case t.Type == TransactionBuy || (t.Type == TransactionAdjust && t.Quantity > 0):
+			lot := Lot{Acquired: t.Date, Quantity: t.Quantity}
+			if t.Price != nil {
+				lot.Cost = t.Quantity**t.Price + t.Fees
+				lot.CostKnown = true
+			}
+			holding.Lots = append(holding.Lots, lot)
+		case t.Type == TransactionSell:
+			proceeds := t.Quantity**t.Price - t.Fees
+			pieces := holding.remove(t.Quantity, method)
+			// AddTransaction rejects sells beyond the holding, so this only happens on data written around it
+			if matched := lotQuantity(pieces); matched < t.Quantity-shareEpsilon {
+				slog.Error("sell is larger than the lots held on its date", slog.String("symbol", symbol), slog.Time("date", t.Date),
+					slog.Float64("quantity", t.Quantity), slog.Float64("matched", matched))
+			}
+			for _, piece := range pieces {
+				holding.Realized = append(holding.Realized, RealizedGain{
+					Symbol:    symbol,
+					Acquired:  piece.Acquired,
+					Sold:      t.Date,
+					Quantity:  piece.Quantity,
+					Proceeds:  proceeds * piece.Quantity / t.Quantity,
+					Cost:      piece.Cost,
+					CostKnown: piece.CostKnown,
+				})
+			}
+		case t.Type == TransactionAdjust:
+			holding.remove(-t.Quantity, method)
+		}
+	}
+	return holding
+}
+
+// remove takes quantity shares out of the lots and returns the pieces taken
+func (h *Holding) remove(quantity float64, method CostMethod) (pieces []Lot) {
+	total := h.Shares()
+	if total <= 0 || quantity <= 0 {
+		return nil
+	}
+	quantity = min(quantity, total)
+
+	if method == CostAverage {
+		fraction := quantity / total
+		for i := range h.Lots {
+			lot := &h.Lots[i]
+			pieces = append(pieces, Lot{Acquired: lot.Acquired, Quantity: lot.Quantity * fraction, Cost: lot.Cost * fraction, CostKnown: lot.CostKnown})
+			lot.Quantity -= lot.Quantity * fraction
+			lot.Cost -= lot.Cost * fraction
+		}
+	} else {
+		for i := range h.Lots {
+			lot := &h.Lots[i]
+			if quantity <= shareEpsilon {
+				break
+			}
+			if lot.Quantity <= shareEpsilon {
+				continue
+			}
+			taken := min(quantity, lot.Quantity)
+			cost := lot.Cost * taken / lot.Quantity
+			pieces = append(pieces, Lot{Acquired: lot.Acquired, Quantity: taken, Cost: cost, CostKnown: lot.CostKnown})
+			lot.Quantity -= taken
+			lot.Cost -= cost
+			quantity -= taken
+		}
+	}
+
+	open := h.Lots[:0]
+	for _, lot := range h.Lots {
+		if lot.Quantity > shareEpsilon {
+			open = append(open, lot)
+		}
+	}
+	h.Lots = open
+	return pieces
+}
+
+// Shares returns the number of shares in the open lots
+func (h Holding) Shares() float64 {
+	return lotQuantity(h.Lots)
+}
+
+func lotQuantity(lots []Lot) (shares float64) {
+	for _, lot := range lots {
+		shares += lot.Quantity
+	}
+	return
+}
+
+// insertByDate returns the transactions, ordered by date, with t placed after the others of its date
+func insertByDate(transactions []Transaction, t Transaction) []Transaction {
+	i := sort.Search(len(transactions), func(i int) bool { return transactions[i].Date.After(t.Date) })
+	replay := make([]Transaction, 0, len(transactions)+1)
+	replay = append(replay, transactions[:i]...)
+	replay = append(replay, t)
+	return append(replay, transactions[i:]...)
+}
+
+// checkSells replays the share count of one symbol, oldest first, and returns ErrInsufficientShares
+// for the first sell that is larger than the shares held on its date
+func checkSells(transactions []Transaction) error {
+	var shares float64
+	for _, t := range transactions {
+		switch t.Type {
+		case TransactionBuy:
+			shares += t.Quantity
+		case TransactionSell:
+			if t.Quantity > shares+shareEpsilon {
+				return fmt.Errorf("%w: holding %.4f %s on %s", ErrInsufficientShares, shares, t.Symbol, t.Date.Format("2006-01-02"))
+			}
+			shares -= t.Quantity
+		case TransactionAdjust:
+			// like BuildHolding, an adjustment below zero closes the holding
+			shares = max(shares+t.Quantity, 0)
+		}
+	}
+	return nil
+}
+
+// CostBasis returns the cost of the open lots with a known cost, and how many shares those are
+func (h Holding) CostBasis() (cost, shares float64) {
+	for _, lot := range h.Lots {
+		if lot.CostKnown {
+			cost += lot.Cost
+			shares += lot.Quantity
+		}
+	}
+	return
+}
+
+// UnrealizedGain returns the gain of the shares with a known cost at price
+func (h Holding) UnrealizedGain(price float64) float64 {
+	cost, shares := h.CostBasis()
+	return shares*price - cost
+}
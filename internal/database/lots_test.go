@@ -0,0 +1,252 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBuildHolding(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	price := func(v float64) *float64 { return &v }
+	buy := func(d int, quantity, p, fees float64) Transaction {
+		return Transaction{Type: TransactionBuy, Date: day(d), Quantity: quantity, Price: price(p), Fees: fees}
+	}
+	sell := func(d int, quantity, p, fees float64) Transaction {
+		return Transaction{Type: TransactionSell, Date: day(d), Quantity: quantity, Price: price(p), Fees: fees}
+	}
+	adjust := func(d int, quantity float64) Transaction {
+		return Transaction{Type: TransactionAdjust, Date: day(d), Quantity: quantity}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		method       CostMethod
+		wantLots     []Lot
+		wantRealized []RealizedGain
+	}{
+		{
+			name:         "fifo sell across lots",
+			transactions: []Transaction{buy(1, 10, 10, 1), buy(2, 10, 20, 0), sell(3, 15, 30, 3)},
+			method:       CostFIFO,
+			wantLots:     []Lot{{Acquired: day(2), Quantity: 5, Cost: 100, CostKnown: true}},
+			wantRealized: []RealizedGain{
+				{Acquired: day(1), Sold: day(3), Quantity: 10, Proceeds: 298, Cost: 101, CostKnown: true},
+				{Acquired: day(2), Sold: day(3), Quantity: 5, Proceeds: 149, Cost: 100, CostKnown: true},
+			},
+		},
+		{
+			name:         "average cost sell",
+			transactions: []Transaction{buy(1, 10, 10, 1), buy(2, 10, 20, 0), sell(3, 15, 30, 3)},
+			method:       CostAverage,
+			wantLots: []Lot{
+				{Acquired: day(1), Quantity: 2.5, Cost: 25.25, CostKnown: true},
+				{Acquired: day(2), Quantity: 2.5, Cost: 50, CostKnown: true},
+			},
+			wantRealized: []RealizedGain{
+				{Acquired: day(1), Sold: day(3), Quantity: 7.5, Proceeds: 223.5, Cost: 75.75, CostKnown: true},
+				{Acquired: day(2), Sold: day(3), Quantity: 7.5, Proceeds: 223.5, Cost: 150, CostKnown: true},
+			},
+		},
+		{
+			name:         "sell of the whole holding",
+			transactions: []Transaction{buy(1, 4, 25, 2), sell(2, 4, 30, 2)},
+			method:       CostFIFO,
+			wantRealized: []RealizedGain{
+				{Acquired: day(1), Sold: day(2), Quantity: 4, Proceeds: 118, Cost: 102, CostKnown: true},
+			},
+		},
+		{
+			name:         "adjust with unknown cost",
+			transactions: []Transaction{adjust(1, 5), buy(2, 5, 10, 0), sell(3, 7, 12, 0)},
+			method:       CostFIFO,
+			wantLots:     []Lot{{Acquired: day(2), Quantity: 3, Cost: 30, CostKnown: true}},
+			wantRealized: []RealizedGain{
+				{Acquired: day(1), Sold: day(3), Quantity: 5, Proceeds: 60},
+				{Acquired: day(2), Sold: day(3), Quantity: 2, Proceeds: 24, Cost: 20, CostKnown: true},
+			},
+		},
+		{
+			name:         "negative adjust",
+			transactions: []Transaction{buy(1, 10, 10, 0), buy(2, 10, 20, 0), adjust(3, -12)},
+			method:       CostFIFO,
+			wantLots:     []Lot{{Acquired: day(2), Quantity: 8, Cost: 160, CostKnown: true}},
+		},
+		{
+			name:         "negative adjust at average cost",
+			transactions: []Transaction{buy(1, 10, 10, 0), buy(2, 10, 20, 0), adjust(3, -10)},
+			method:       CostAverage,
+			wantLots: []Lot{
+				{Acquired: day(1), Quantity: 5, Cost: 50, CostKnown: true},
+				{Acquired: day(2), Quantity: 5, Cost: 100, CostKnown: true},
+			},
+		},
+		{
+			name:         "adjust below zero closes the holding",
+			transactions: []Transaction{buy(1, 10, 10, 0), adjust(2, -15)},
+			method:       CostFIFO,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			holding := BuildHolding("ACME", tt.transactions, tt.method)
+
+			if len(holding.Lots) != len(tt.wantLots) {
+				t.Fatalf("lots = %+v, want %+v", holding.Lots, tt.wantLots)
+			}
+			for i, want := range tt.wantLots {
+				got := holding.Lots[i]
+				if !got.Acquired.Equal(want.Acquired) || !near(got.Quantity, want.Quantity) || !near(got.Cost, want.Cost) || got.CostKnown != want.CostKnown {
+					t.Errorf("lot %d = %+v, want %+v", i, got, want)
+				}
+			}
+
+			if len(holding.Realized) != len(tt.wantRealized) {
+				t.Fatalf("realized = %+v, want %+v", holding.Realized, tt.wantRealized)
+			}
+			for i, want := range tt.wantRealized {
+				got := holding.Realized[i]
+				if got.Symbol != "ACME" || !got.Acquired.Equal(want.Acquired) || !got.Sold.Equal(want.Sold) ||
+					!near(got.Quantity, want.Quantity) || !near(got.Proceeds, want.Proceeds) || !near(got.Cost, want.Cost) || got.CostKnown != want.CostKnown {
+					t.Errorf("realized %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHoldingCostBasis(t *testing.T) {
+	holding := Holding{Lots: []Lot{
+		{Quantity: 5},
+		{Quantity: 3, Cost: 30, CostKnown: true},
+		{Quantity: 2, Cost: 40, CostKnown: true},
+	}}
+
+	if got := holding.Shares(); got != 10 {
+		t.Errorf("Shares = %v, want 10", got)
+	}
+	// the lot without a known cost is left out of the basis and the gain
+	if cost, shares := holding.CostBasis(); cost != 70 || shares != 5 {
+		t.Errorf("CostBasis = %v for %v shares, want 70 for 5", cost, shares)
+	}
+	if got := holding.UnrealizedGain(20); got != 30 {
+		t.Errorf("UnrealizedGain = %v, want 30", got)
+	}
+}
+
+func TestParseCostMethod(t *testing.T) {
+	tests := []struct {
+		method  string
+		want    CostMethod
+		wantErr bool
+	}{
+		{method: "", want: CostFIFO},
+		{method: "fifo", want: CostFIFO},
+		{method: "average", want: CostAverage},
+		{method: "lifo", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCostMethod(tt.method)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseCostMethod(%q) = %q, %v, want %q", tt.method, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheckSellsOutOfOrder(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	price := func(v float64) *float64 { return &v }
+	trade := func(kind string, d int, quantity float64) Transaction {
+		return Transaction{Symbol: "ACME", Type: kind, Date: day(d), Quantity: quantity, Price: price(10)}
+	}
+	recorded := []Transaction{trade(TransactionBuy, 5, 10), trade(TransactionSell, 10, 6)}
+
+	tests := []struct {
+		name    string
+		insert  Transaction
+		wantErr bool
+	}{
+		{name: "sell after the buy", insert: trade(TransactionSell, 12, 4)},
+		{name: "sell on the day of the buy", insert: trade(TransactionSell, 5, 4)},
+		{name: "sell before the buy", insert: trade(TransactionSell, 3, 1), wantErr: true},
+		{name: "sell beyond the holding", insert: trade(TransactionSell, 12, 5), wantErr: true},
+		{name: "backdated sell leaving a later sell short", insert: trade(TransactionSell, 7, 5), wantErr: true},
+		{name: "backdated buy", insert: trade(TransactionBuy, 1, 5)},
+		{name: "backdated adjust leaving a later sell short", insert: Transaction{Symbol: "ACME", Type: TransactionAdjust, Date: day(8), Quantity: -5}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay := insertByDate(recorded, tt.insert)
+			for i := 1; i < len(replay); i++ {
+				if replay[i].Date.Before(replay[i-1].Date) {
+					t.Fatalf("replay isn't ordered by date: %+v", replay)
+				}
+			}
+
+			err := checkSells(replay)
+			if tt.wantErr != errors.Is(err, ErrInsufficientShares) {
+				t.Fatalf("checkSells = %v, want an error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			// without a rejected sell the lots always add up to the share count
+			holding := BuildHolding("ACME", replay, CostFIFO)
+			var shares float64
+			for _, t := range replay {
+				switch t.Type {
+				case TransactionSell:
+					shares -= t.Quantity
+				default:
+					shares += t.Quantity
+				}
+			}
+			if !near(holding.Shares(), shares) {
+				t.Errorf("lots hold %v shares, want %v", holding.Shares(), shares)
+			}
+		})
+	}
+}
+
+func TestAddTransactionRejectsBackdatedSell(t *testing.T) {
+	ctx := context.Background()
+	price := func(v float64) *float64 { return &v }
+	trade := func(kind string, days int, quantity float64) Transaction {
+		return Transaction{UserID: "backdated", Portfolio: DefaultPortfolio, Symbol: "ACME", Type: kind, Date: daysAgo(days), Quantity: quantity, Price: price(100)}
+	}
+
+	if err := AddTransaction(ctx, trade(TransactionBuy, 10, 5)); err != nil {
+		t.Fatalf("AddTransaction: %v", err)
+	}
+	if err := AddTransaction(ctx, trade(TransactionSell, 2, 4)); err != nil {
+		t.Fatalf("AddTransaction: %v", err)
+	}
+
+	for _, rejected := range []Transaction{trade(TransactionSell, 20, 1), trade(TransactionSell, 5, 2)} {
+		if err := AddTransaction(ctx, rejected); !errors.Is(err, ErrInsufficientShares) {
+			t.Errorf("AddTransaction(sell %v on %s) err = %v, want ErrInsufficientShares", rejected.Quantity, rejected.Date.Format("2006-01-02"), err)
+		}
+	}
+
+	portfolio, err := GetPortfolio(ctx, "backdated", DefaultPortfolio, "ACME")
+	if err != nil {
+		t.Fatalf("GetPortfolio: %v", err)
+	}
+	transactions, err := GetTransactions(ctx, "backdated", DefaultPortfolio, "ACME")
+	if err != nil {
+		t.Fatalf("GetTransactions: %v", err)
+	}
+	if len(transactions) != 2 || portfolio.Shares != 1 {
+		t.Errorf("%d transactions holding %v shares, want the 2 accepted ones holding 1", len(transactions), portfolio.Shares)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
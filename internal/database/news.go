@@ -0,0 +1,58 @@
+package database
+
+import (
+	"context"
+	"time"
+)
+
+// GetNewsWatchLists returns the watched stocks with news turned on, triggered price alerts included
+func GetNewsWatchLists(ctx context.Context) (watchlists []WatchList, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := duckdbClient.QueryContext(ctx, `
+		SELECT user_id, symbol, price_target, direction, triggered, news_since, COALESCE(channel_id, '')
+		FROM watchlists
+		WHERE news_since IS NOT NULL;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var watchlist WatchList
+		if err := rows.Scan(&watchlist.UserID, &watchlist.Symbol, &watchlist.PriceTarget, &watchlist.Direction, &watchlist.Triggered, &watchlist.NewsSince, &watchlist.ChannelID); err != nil {
+			return nil, err
+		}
+		watchlists = append(watchlists, watchlist)
+	}
+	return watchlists, rows.Err()
+}
+
+// ClaimNews records that the headline is sent to the user.
+// It returns false when it was already sent, so concurrent checks never send it twice.
+func ClaimNews(ctx context.Context, userID, symbol, newsID string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := duckdbClient.ExecContext(ctx, `
+		INSERT INTO news_sent (user_id, news_id, symbol, sent_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT DO NOTHING;
+	`, userID, newsID, symbol, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	claimed, err := result.RowsAffected()
+	return claimed > 0, err
+}
+
+// ReleaseNews forgets a claimed headline so the next check retries it, used when the DM failed
+func ReleaseNews(ctx context.Context, userID, newsID string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	_, err := duckdbClient.ExecContext(ctx, `DELETE FROM news_sent WHERE user_id = ? AND news_id = ?;`, userID, newsID)
+	return err
+}
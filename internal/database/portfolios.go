@@ -0,0 +1,141 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultPortfolio is the portfolio used when none is named, it is created on first use
+const DefaultPortfolio = "default"
+
+// maxPortfolioName keeps the name short enough for the button custom IDs
+const maxPortfolioName = 32
+
+var (
+	// ErrUnknownPortfolio is returned when the user has no portfolio with the name
+	ErrUnknownPortfolio = errors.New("unknown portfolio")
+	// ErrPortfolioExists is returned when creating a portfolio the user already has
+	ErrPortfolioExists = errors.New("portfolio already exists")
+)
+
+// PortfolioHeader is a named portfolio of a user, the holdings and transactions reference it by name
+type PortfolioHeader struct {
+	UserID    string
+	Name      string
+	CreatedAt time.Time
+}
+
+// ParsePortfolioName returns the portfolio name for a command option, the default portfolio when empty
+func ParsePortfolioName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	switch {
+	case name == "":
+		return DefaultPortfolio, nil
+	case len(name) > maxPortfolioName:
+		return "", fmt.Errorf("portfolio name is longer than %d characters", maxPortfolioName)
+	case strings.Contains(name, ";"):
+		return "", fmt.Errorf("portfolio name can't contain ';'")
+	}
+	return name, nil
+}
+
+// GetPortfolioHeaders returns the portfolios of the user ordered by name
+func GetPortfolioHeaders(ctx context.Context, userID string) (headers []PortfolioHeader, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := duckdbClient.QueryContext(ctx, `
+		SELECT user_id, name, created_at
+		FROM portfolio_headers
+		WHERE user_id = ?
+		ORDER BY name ASC;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var header PortfolioHeader
+		if err := rows.Scan(&header.UserID, &header.Name, &header.CreatedAt); err != nil {
+			return nil, err
+		}
+		headers = append(headers, header)
+	}
+	return headers, rows.Err()
+}
+
+// CreatePortfolio adds an empty portfolio for the user, ErrPortfolioExists when the name is taken
+func CreatePortfolio(ctx context.Context, userID, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	result, err := duckdbClient.ExecContext(ctx, `
+		INSERT INTO portfolio_headers (user_id, name, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT DO NOTHING;
+	`, userID, name, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if created, err := result.RowsAffected(); err != nil {
+		return err
+	} else if created == 0 {
+		return fmt.Errorf("%w: %s", ErrPortfolioExists, name)
+	}
+	return nil
+}
+
+// DeletePortfolio removes the portfolio with its holdings, transactions and cash
+func DeletePortfolio(ctx context.Context, userID, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkPortfolio(ctx, tx, userID, name); err != nil {
+		return err
+	}
+	for _, query := range []string{
+		`DELETE FROM portfolios WHERE user_id = ? AND portfolio = ?;`,
+		`DELETE FROM transactions WHERE user_id = ? AND portfolio = ?;`,
+		`DELETE FROM cash_ledger WHERE user_id = ? AND portfolio = ?;`,
+		`DELETE FROM portfolio_headers WHERE user_id = ? AND name = ?;`,
+	} {
+		if _, err := tx.ExecContext(ctx, query, userID, name); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// checkPortfolio returns ErrUnknownPortfolio when the user has no portfolio with the name.
+// The default portfolio always exists, it is created here when missing.
+func checkPortfolio(ctx context.Context, tx *sql.Tx, userID, name string) error {
+	if name == DefaultPortfolio {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO portfolio_headers (user_id, name, created_at)
+			VALUES (?, ?, ?)
+			ON CONFLICT DO NOTHING;
+		`, userID, name, time.Now().UTC())
+		return err
+	}
+
+	var exists bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM portfolio_headers WHERE user_id = ? AND name = ?);`, userID, name).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrUnknownPortfolio, name)
+	}
+	return nil
+}
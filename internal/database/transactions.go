@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// TransactionBuy adds shares at a price
+	TransactionBuy = "buy"
+	// TransactionSell removes shares at a price and realizes the gain
+	TransactionSell = "sell"
+	// TransactionAdjust sets the share count without a trade, like /portfolio add, the cost of added shares is unknown
+	TransactionAdjust = "adjust"
+)
+
+// shareEpsilon is the share count below which a holding counts as closed
+const shareEpsilon = 1e-9
+
+// ErrInsufficientShares is returned when a sell is larger than the holding
+var ErrInsufficientShares = errors.New("not enough shares to sell")
+
+type Transaction struct {
+	ID     int64
+	UserID string
+	// Portfolio is the name of the portfolio the trade belongs to
+	Portfolio string
+	Symbol    string
+	Type      string
+	Date      time.Time
+	Quantity  float64
+	// Price is nil for adjustments, whose cost isn't known
+	Price *float64
+	Fees  float64
+}
+
+func (t Transaction) Values() []interface{} {
+	return []interface{}{t.UserID, t.Portfolio, t.Symbol, t.Type, t.Date, t.Quantity, t.Price, t.Fees}
+}
+
+// GetTransactions returns the transactions of a portfolio for a symbol in the order they happened
+func GetTransactions(ctx context.Context, userID, portfolio, symbol string) ([]Transaction, error) {
+	return queryTransactions(ctx, duckdbClient, `WHERE user_id = ? AND portfolio = ? AND symbol = ?`, userID, portfolio, symbol)
+}
+
+// GetPortfolioTransactions returns all transactions of a portfolio in the order they happened
+func GetPortfolioTransactions(ctx context.Context, userID, portfolio string) ([]Transaction, error) {
+	return queryTransactions(ctx, duckdbClient, `WHERE user_id = ? AND portfolio = ?`, userID, portfolio)
+}
+
+// querier is the part of *sql.DB and *sql.Tx queryTransactions needs, so a transaction sees its own writes
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func queryTransactions(ctx context.Context, db querier, where string, args ...interface{}) (transactions []Transaction, err error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id, user_id, portfolio, symbol, type, date, quantity, price, fees
+		FROM transactions
+		%s
+		ORDER BY date ASC, id ASC;
+	`, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t Transaction
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Portfolio, &t.Symbol, &t.Type, &t.Date, &t.Quantity, &t.Price, &t.Fees); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	return transactions, rows.Err()
+}
+
+// AddTransaction records a buy or sell and updates the share count and cash of the portfolio with it.
+// A sell larger than the holding on its date, or a trade that leaves a later sell without enough shares,
+// returns ErrInsufficientShares, a missing portfolio ErrUnknownPortfolio.
+func AddTransaction(ctx context.Context, t Transaction) error {
+	if t.Type != TransactionBuy && t.Type != TransactionSell {
+		return fmt.Errorf("unknown transaction type: %q", t.Type)
+	}
+	if t.Quantity <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if err := AddTrackedStock(ctx, t.Symbol); err != nil {
+		return err
+	}
+	currency, err := symbolCurrency(ctx, t.Symbol)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	tx, err := duckdbClient.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkPortfolio(ctx, tx, t.UserID, t.Portfolio); err != nil {
+		return err
+	}
+	shares, err := currentShares(ctx, tx, t.UserID, t.Portfolio, t.Symbol)
+	if err != nil {
+		return err
+	}
+
+	// the trade can be dated before earlier recorded ones, so every sell is checked against the shares held on its date
+	history, err := queryTransactions(ctx, tx, `WHERE user_id = ? AND portfolio = ? AND symbol = ?`, t.UserID, t.Portfolio, t.Symbol)
+	if err != nil {
+		return err
+	}
+	if err := checkSells(insertByDate(history, t)); err != nil {
+		return err
+	}
+
+	delta := t.Quantity
+	if t.Type == TransactionSell {
+		delta = -t.Quantity
+	}
+
+	if err := insertTransaction(ctx, tx, t); err != nil {
+		return err
+	}
+	if err := setShares(ctx, tx, t.UserID, t.Portfolio, t.Symbol, shares+delta); err != nil {
+		return err
+	}
+	if err := insertCash(ctx, tx, tradeCash(t, currency)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// currentShares returns the share count of the holding, 0 when there is none
+func currentShares(ctx context.Context, tx *sql.Tx, userID, portfolio, symbol string) (float64, error) {
+	var shares float64
+	err := tx.QueryRowContext(ctx, `SELECT shares FROM portfolios WHERE user_id = ? AND portfolio = ? AND symbol = ?;`, userID, portfolio, symbol).Scan(&shares)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return shares, err
+}
+
+func insertTransaction(ctx context.Context, tx *sql.Tx, t Transaction) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO transactions (user_id, portfolio, symbol, type, date, quantity, price, fees)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?);
+	`, t.Values()...)
+	return err
+}
+
+// setShares stores the share count of the holding, a closed holding is removed from the portfolio
+func setShares(ctx context.Context, tx *sql.Tx, userID, portfolio, symbol string, shares float64) error {
+	if math.Abs(shares) < shareEpsilon {
+		_, err := tx.ExecContext(ctx, `DELETE FROM portfolios WHERE user_id = ? AND portfolio = ? AND symbol = ?;`, userID, portfolio, symbol)
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO portfolios (user_id, portfolio, symbol, shares)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT DO UPDATE SET
+		shares = EXCLUDED.shares;
+	`, userID, portfolio, symbol, shares)
+	return err
+}
@@ -0,0 +1,110 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/disgoorg/snowflake/v2"
+	"github.com/stollenaar/stockbot/internal/database"
+)
+
+var (
+	// ErrNoGuild is returned when shared data is asked for outside of a server
+	ErrNoGuild = errors.New("shared portfolios and watchlists only exist in a server")
+	// ErrForbidden is returned when the member may not change the shared data
+	ErrForbidden = errors.New("not allowed to change the shared data")
+	// ErrNotManager is returned when a guild setting is changed without the Manage Server permission
+	ErrNotManager = errors.New("needs the Manage Server permission")
+)
+
+// ForbiddenError is returned when the member may not change the shared data, Role is the member role when one is set
+type ForbiddenError struct {
+	Role string
+}
+
+func (e *ForbiddenError) Error() string {
+	if e.Role == "" {
+		return "not allowed to change the shared data, the guild has no member role"
+	}
+	return fmt.Sprintf("not allowed to change the shared data without role %s", e.Role)
+}
+
+func (e *ForbiddenError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+// Owner is who the data of a command belongs to, the invoking user or the guild for shared data
+type Owner struct {
+	ID    string
+	Guild bool
+}
+
+// Resolve returns the owner of the data a command works on, the guild when shared is set
+func Resolve(userID snowflake.ID, guildID *snowflake.ID, shared bool) (Owner, error) {
+	if !shared {
+		return Owner{ID: userID.String()}, nil
+	}
+	if guildID == nil {
+		return Owner{}, ErrNoGuild
+	}
+	return Owner{ID: guildID.String(), Guild: true}, nil
+}
+
+// CanManage reports whether the member may change the settings of the guild, like the member role
+func CanManage(member *discord.ResolvedMember) bool {
+	return member != nil && member.Permissions.Has(discord.PermissionManageGuild)
+}
+
+// CheckManage returns ErrNotManager when the data is shared and the member can't manage the guild
+func CheckManage(owner Owner, member *discord.ResolvedMember) error {
+	if owner.Guild && !CanManage(member) {
+		return ErrNotManager
+	}
+	return nil
+}
+
+// CheckEdit returns a ForbiddenError unless the member may change the data of the owner.
+// Users always own their data, shared data needs the member role of the guild or the Manage Server permission.
+func CheckEdit(ctx context.Context, owner Owner, member *discord.ResolvedMember) error {
+	if !owner.Guild || CanManage(member) {
+		return nil
+	}
+	if member == nil {
+		return &ForbiddenError{}
+	}
+
+	role, err := database.GetGuildMemberRole(ctx, owner.ID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return &ForbiddenError{}
+	}
+	roleID, err := snowflake.Parse(role)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(member.RoleIDs, roleID) {
+		return &ForbiddenError{Role: role}
+	}
+	return nil
+}
+
+// ErrorMessage explains a missing guild or permission to the user, other errors get the fallback
+func ErrorMessage(err error, fallback string) string {
+	var forbidden *ForbiddenError
+	switch {
+	case errors.Is(err, ErrNoGuild):
+		return "Shared portfolios and watchlists are only available in a server"
+	case errors.Is(err, ErrNotManager):
+		return "Only members with the Manage Server permission can do this for the server"
+	case errors.As(err, &forbidden) && forbidden.Role != "":
+		return fmt.Sprintf("You need the <@&%s> role or the Manage Server permission to change the shared data of this server", forbidden.Role)
+	case errors.Is(err, ErrForbidden):
+		return "Only members with the Manage Server permission can change the shared data of this server until a member role is set with /portfolio role"
+	}
+	return fallback
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/disgoorg/disgo/discord"
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+)
+
+// Discord limits on autocomplete responses
+const (
+	maxAutocompleteChoices = 25
+	maxChoiceLength        = 100
+)
+
+// SymbolChoices searches the tickers matching query and returns them as autocomplete choices.
+// The choice shows the symbol, name and exchange, the value is the symbol itself.
+func SymbolChoices(ctx context.Context, provider yfa.MarketDataProvider, query string) []discord.AutocompleteChoice {
+	choices := []discord.AutocompleteChoice{}
+
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return choices
+	}
+
+	results, err := provider.Search(ctx, query)
+	if err != nil {
+		slog.Error("Error searching symbols:", slog.Any("err", err), slog.String("query", query))
+		return choices
+	}
+
+	for _, result := range results {
+		if len(choices) == maxAutocompleteChoices {
+			break
+		}
+		if len(result.Symbol) > maxChoiceLength {
+			continue
+		}
+
+		name := result.Symbol
+		if result.Name != "" {
+			name = fmt.Sprintf("%s - %s", name, result.Name)
+		}
+		if result.Exchange != "" {
+			name = fmt.Sprintf("%s (%s)", name, result.Exchange)
+		}
+		if len([]rune(name)) > maxChoiceLength {
+			name = string([]rune(name)[:maxChoiceLength-1]) + "…"
+		}
+
+		choices = append(choices, discord.AutocompleteChoiceString{
+			Name:  name,
+			Value: result.Symbol,
+		})
+	}
+	return choices
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -24,6 +26,21 @@ type Config struct {
 	TERMINAL_REGEX string
 
 	ADMIN_USER_ID string
+
+	// YFA_CASSETTE is the cassette file Yahoo responses are recorded to or replayed from, empty disables it
+	YFA_CASSETTE string
+	// YFA_CASSETTE_MODE is either record or replay
+	YFA_CASSETTE_MODE string
+	// YFA_BASE_URL points the Yahoo client at another server, like the fakeyahoo one, empty uses Yahoo
+	YFA_BASE_URL string
+
+	// DATA_PROVIDERS lists the market data providers in priority order, like "yahoo,stooq"
+	DATA_PROVIDERS []string
+	// STOOQ_URL is the CSV url template of the Stooq provider, see yfa.DefaultStooqURL
+	STOOQ_URL string
+
+	// RISK_FREE_RATE is the annual risk-free rate used to price options, 0.04 is 4%
+	RISK_FREE_RATE float64
 }
 
 var (
@@ -48,11 +65,25 @@ func init() {
 		TERMINAL_REGEX:     os.Getenv("TERMINAL_REGEX"),
 		DUCKDB_PATH:        os.Getenv("DUCKDB_PATH"),
 		ADMIN_USER_ID:      os.Getenv("ADMIN_USER_ID"),
+		YFA_CASSETTE:       os.Getenv("YFA_CASSETTE"),
+		YFA_CASSETTE_MODE:  os.Getenv("YFA_CASSETTE_MODE"),
+		YFA_BASE_URL:       os.Getenv("YFA_BASE_URL"),
+		STOOQ_URL:          os.Getenv("STOOQ_URL"),
 	}
 	if ConfigFile.TERMINAL_REGEX == "" {
 		ConfigFile.TERMINAL_REGEX = `(\.|,|:|;|\?|!)$`
 	}
 
+	ConfigFile.DATA_PROVIDERS = []string{"yahoo"}
+	if providers := os.Getenv("DATA_PROVIDERS"); providers != "" {
+		ConfigFile.DATA_PROVIDERS = strings.Split(strings.ReplaceAll(strings.ToLower(providers), " ", ""), ",")
+	}
+
+	ConfigFile.RISK_FREE_RATE = 0.04
+	if rate, err := strconv.ParseFloat(os.Getenv("RISK_FREE_RATE"), 64); err == nil {
+		ConfigFile.RISK_FREE_RATE = rate
+	}
+
 }
 
 func init() {
@@ -0,0 +1,127 @@
+package pricing
+
+import (
+	"math"
+	"time"
+
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+)
+
+// minVolatility is the implied volatility below which Yahoo's value is treated as missing
+const minVolatility = 0.001
+
+// marketClose is the offset from the expiration date (UTC midnight) to when US options expire, 16:00 New York time
+const marketClose = 20 * time.Hour
+
+// Greeks holds the Black-Scholes theoretical value and sensitivities of a single contract.
+// Theta is per calendar day, Vega and Rho are per percentage point.
+type Greeks struct {
+	Value float64
+	Delta float64
+	Gamma float64
+	Theta float64
+	Vega  float64
+	Rho   float64
+}
+
+// BlackScholes prices a European option on a non-dividend paying underlying.
+// years is the time to expiration, rate the continuously compounded risk-free rate and volatility the annualized implied volatility.
+func BlackScholes(call bool, spot, strike, years, rate, volatility float64) Greeks {
+	if years <= 0 || volatility <= 0 {
+		return intrinsic(call, spot, strike)
+	}
+
+	sqrtT := math.Sqrt(years)
+	d1 := (math.Log(spot/strike) + (rate+volatility*volatility/2)*years) / (volatility * sqrtT)
+	d2 := d1 - volatility*sqrtT
+	discount := math.Exp(-rate * years)
+
+	greeks := Greeks{
+		Gamma: normPDF(d1) / (spot * volatility * sqrtT),
+		Vega:  spot * normPDF(d1) * sqrtT / 100,
+	}
+	decay := -spot * normPDF(d1) * volatility / (2 * sqrtT)
+
+	if call {
+		greeks.Value = spot*normCDF(d1) - strike*discount*normCDF(d2)
+		greeks.Delta = normCDF(d1)
+		greeks.Theta = (decay - rate*strike*discount*normCDF(d2)) / 365
+		greeks.Rho = strike * years * discount * normCDF(d2) / 100
+	} else {
+		greeks.Value = strike*discount*normCDF(-d2) - spot*normCDF(-d1)
+		greeks.Delta = normCDF(d1) - 1
+		greeks.Theta = (decay + rate*strike*discount*normCDF(-d2)) / 365
+		greeks.Rho = -strike * years * discount * normCDF(-d2) / 100
+	}
+	return greeks
+}
+
+// ForOption prices the contract at now using its implied volatility.
+// It returns false when the contract can't be priced, like without an underlying price or strike.
+func ForOption(option yfa.OptionDetail, call bool, spot, rate float64, now time.Time) (Greeks, bool) {
+	if spot <= 0 || option.Strike <= 0 {
+		return Greeks{}, false
+	}
+
+	expiration, err := time.Parse("2006-01-02", option.Expiration)
+	if err != nil {
+		return Greeks{}, false
+	}
+	years := expiration.Add(marketClose).Sub(now).Hours() / 24 / 365
+
+	// Yahoo reports a near zero volatility for contracts without recent quotes, solve it from the last price instead
+	volatility := option.ImpliedVolatility
+	if volatility < minVolatility && option.LastPrice > 0 {
+		if iv, ok := ImpliedVolatility(call, option.LastPrice, spot, option.Strike, years, rate); ok {
+			volatility = iv
+		}
+	}
+
+	return BlackScholes(call, spot, option.Strike, years, rate, volatility), true
+}
+
+// ImpliedVolatility solves for the volatility at which BlackScholes values the contract at price.
+// It returns false when no volatility between 0.1% and 500% reproduces the price, like for a price below the intrinsic value.
+func ImpliedVolatility(call bool, price, spot, strike, years, rate float64) (float64, bool) {
+	if price <= 0 || spot <= 0 || strike <= 0 || years <= 0 {
+		return 0, false
+	}
+
+	low, high := minVolatility, 5.0
+	if price < BlackScholes(call, spot, strike, years, rate, low).Value || price > BlackScholes(call, spot, strike, years, rate, high).Value {
+		return 0, false
+	}
+
+	// the value rises monotonically with the volatility, so bisect until the bracket is below 0.0001%
+	for high-low > 1e-6 {
+		mid := (low + high) / 2
+		if BlackScholes(call, spot, strike, years, rate, mid).Value < price {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2, true
+}
+
+// intrinsic returns the value of an expired contract, or one without volatility
+func intrinsic(call bool, spot, strike float64) Greeks {
+	switch {
+	case call && spot > strike:
+		return Greeks{Value: spot - strike, Delta: 1}
+	case !call && spot < strike:
+		return Greeks{Value: strike - spot, Delta: -1}
+	default:
+		return Greeks{}
+	}
+}
+
+// normCDF is the cumulative distribution function of the standard normal distribution
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// normPDF is the probability density function of the standard normal distribution
+func normPDF(x float64) float64 {
+	return math.Exp(-x*x/2) / math.Sqrt(2*math.Pi)
+}
@@ -0,0 +1,170 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+)
+
+func near(got, want, tolerance float64) bool {
+	return math.Abs(got-want) <= tolerance
+}
+
+// The reference values are the worked examples of Hull, Options, Futures, and Other Derivatives
+func TestBlackScholesPrice(t *testing.T) {
+	tests := []struct {
+		name                           string
+		call                           bool
+		spot, strike, years, rate, vol float64
+		want                           float64
+	}{
+		// Example 15.6: S=42, K=40, r=10%, σ=20%, T=0.5
+		{name: "call", call: true, spot: 42, strike: 40, years: 0.5, rate: 0.1, vol: 0.2, want: 4.76},
+		{name: "put", call: false, spot: 42, strike: 40, years: 0.5, rate: 0.1, vol: 0.2, want: 0.81},
+		// Table 19.1: S=49, K=50, r=5%, σ=20%, T=20 weeks
+		{name: "twenty week call", call: true, spot: 49, strike: 50, years: 0.3846, rate: 0.05, vol: 0.2, want: 2.40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BlackScholes(tt.call, tt.spot, tt.strike, tt.years, tt.rate, tt.vol).Value
+			if !near(got, tt.want, 0.005) {
+				t.Errorf("Value = %.4f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlackScholesPutCallParity(t *testing.T) {
+	spot, strike, years, rate, vol := 42.0, 40.0, 0.5, 0.1, 0.2
+	call := BlackScholes(true, spot, strike, years, rate, vol)
+	put := BlackScholes(false, spot, strike, years, rate, vol)
+
+	parity := spot - strike*math.Exp(-rate*years)
+	if got := call.Value - put.Value; !near(got, parity, 1e-9) {
+		t.Errorf("call - put = %.6f, want %.6f", got, parity)
+	}
+	if got := call.Delta - put.Delta; !near(got, 1, 1e-9) {
+		t.Errorf("call delta - put delta = %.6f, want 1", got)
+	}
+	if call.Gamma != put.Gamma || call.Vega != put.Vega {
+		t.Errorf("gamma and vega differ between the call %+v and put %+v", call, put)
+	}
+}
+
+// Sections 19.4 to 19.9 work out the Greeks of the twenty week call, theta per year and vega and rho per unit
+func TestBlackScholesGreeks(t *testing.T) {
+	greeks := BlackScholes(true, 49, 50, 0.3846, 0.05, 0.2)
+
+	tests := []struct {
+		name      string
+		got       float64
+		want      float64
+		tolerance float64
+	}{
+		{name: "delta", got: greeks.Delta, want: 0.522, tolerance: 0.0005},
+		{name: "gamma", got: greeks.Gamma, want: 0.066, tolerance: 0.0005},
+		{name: "theta per day", got: greeks.Theta, want: -4.31 / 365, tolerance: 0.005 / 365},
+		{name: "vega per point", got: greeks.Vega, want: 12.1 / 100, tolerance: 0.05 / 100},
+		{name: "rho per point", got: greeks.Rho, want: 8.91 / 100, tolerance: 0.005 / 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !near(tt.got, tt.want, tt.tolerance) {
+				t.Errorf("%s = %.6f, want %.6f", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlackScholesEdgeCases(t *testing.T) {
+	tests := []struct {
+		name                           string
+		call                           bool
+		spot, strike, years, rate, vol float64
+		want                           Greeks
+	}{
+		{name: "expired call in the money", call: true, spot: 55, strike: 50, years: 0, rate: 0.05, vol: 0.2, want: Greeks{Value: 5, Delta: 1}},
+		{name: "expired call out of the money", call: true, spot: 45, strike: 50, years: 0, rate: 0.05, vol: 0.2, want: Greeks{}},
+		{name: "expired put in the money", call: false, spot: 45, strike: 50, years: 0, rate: 0.05, vol: 0.2, want: Greeks{Value: 5, Delta: -1}},
+		{name: "expired put out of the money", call: false, spot: 55, strike: 50, years: 0, rate: 0.05, vol: 0.2, want: Greeks{}},
+		{name: "past expiration", call: true, spot: 55, strike: 50, years: -0.1, rate: 0.05, vol: 0.2, want: Greeks{Value: 5, Delta: 1}},
+		{name: "zero volatility call", call: true, spot: 55, strike: 50, years: 0.5, rate: 0.05, vol: 0, want: Greeks{Value: 5, Delta: 1}},
+		{name: "zero volatility put", call: false, spot: 45, strike: 50, years: 0.5, rate: 0.05, vol: 0, want: Greeks{Value: 5, Delta: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BlackScholes(tt.call, tt.spot, tt.strike, tt.years, tt.rate, tt.vol)
+			if got != tt.want {
+				t.Errorf("BlackScholes = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImpliedVolatility(t *testing.T) {
+	// Section 15.11: a call with S=21, K=20, r=10% and T=0.25 trading at 1.875 implies σ=23.5%
+	iv, ok := ImpliedVolatility(true, 1.875, 21, 20, 0.25, 0.1)
+	if !ok || !near(iv, 0.235, 0.0005) {
+		t.Errorf("ImpliedVolatility = %.4f, %v, want 0.235", iv, ok)
+	}
+
+	// solving the price of a known volatility returns that volatility
+	for _, call := range []bool{true, false} {
+		price := BlackScholes(call, 42, 40, 0.5, 0.1, 0.2).Value
+		iv, ok := ImpliedVolatility(call, price, 42, 40, 0.5, 0.1)
+		if !ok || !near(iv, 0.2, 1e-5) {
+			t.Errorf("ImpliedVolatility(call=%v) = %.6f, %v, want 0.2", call, iv, ok)
+		}
+	}
+
+	tests := []struct {
+		name                             string
+		price, spot, strike, years, rate float64
+	}{
+		{name: "below intrinsic", price: 0.5, spot: 42, strike: 40, years: 0.5, rate: 0.1},
+		{name: "above spot", price: 50, spot: 42, strike: 40, years: 0.5, rate: 0.1},
+		{name: "expired", price: 2, spot: 42, strike: 40, years: 0, rate: 0.1},
+		{name: "no price", price: 0, spot: 42, strike: 40, years: 0.5, rate: 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if iv, ok := ImpliedVolatility(true, tt.price, tt.spot, tt.strike, tt.years, tt.rate); ok {
+				t.Errorf("ImpliedVolatility = %.4f, want no solution", iv)
+			}
+		})
+	}
+}
+
+func TestForOption(t *testing.T) {
+	now := time.Date(2024, 10, 17, 20, 0, 0, 0, time.UTC)
+	option := yfa.OptionDetail{Strike: 40, Expiration: "2025-04-17", ImpliedVolatility: 0.2}
+
+	greeks, ok := ForOption(option, true, 42, 0.1, now)
+	if !ok {
+		t.Fatal("ForOption: want a price")
+	}
+	// 182 days to the 16:00 New York close on the expiration date
+	want := BlackScholes(true, 42, 40, 182.0/365, 0.1, 0.2)
+	if !near(greeks.Value, want.Value, 1e-9) {
+		t.Errorf("Value = %.4f, want %.4f", greeks.Value, want.Value)
+	}
+
+	// a missing volatility is solved from the last price
+	option.ImpliedVolatility = 0.00001
+	option.LastPrice = want.Value
+	greeks, ok = ForOption(option, true, 42, 0.1, now)
+	if !ok || !near(greeks.Value, want.Value, 1e-4) {
+		t.Errorf("Value = %.4f, %v, want %.4f from the last price", greeks.Value, ok, want.Value)
+	}
+
+	if _, ok := ForOption(yfa.OptionDetail{Strike: 40, Expiration: "2025-04-17"}, true, 0, 0.1, now); ok {
+		t.Error("ForOption without a spot price: want false")
+	}
+	if _, ok := ForOption(yfa.OptionDetail{Strike: 40, Expiration: "soon"}, true, 42, 0.1, now); ok {
+		t.Error("ForOption with a bad expiration: want false")
+	}
+}
@@ -0,0 +1,22 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+)
+
+// SymbolErrorMessage returns the user-facing message for err, or fallback when it isn't about the symbol
+func SymbolErrorMessage(err error, fallback string) string {
+	var unknown *yfa.UnknownSymbolError
+	if !errors.As(err, &unknown) {
+		return fallback
+	}
+
+	if len(unknown.Suggestions) == 0 {
+		return fmt.Sprintf("Could not find the symbol `%s`. Pick one of the suggestions while typing the symbol.", unknown.Symbol)
+	}
+	return fmt.Sprintf("Could not find the symbol `%s`. Did you mean `%s`?", unknown.Symbol, strings.Join(unknown.Suggestions, "`, `"))
+}
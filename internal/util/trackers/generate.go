@@ -2,6 +2,7 @@ package trackers
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"log/slog"
 	"maps"
@@ -23,10 +24,21 @@ var (
 	WORKING_DIR, _ = os.Getwd()
 )
 
+// PeriodChange returns the percentage change over the period, ending at the latest bar in hist.
+// Anchoring on the latest bar keeps the dates in the exchange's calendar instead of the server's.
 func PeriodChange(period string, hist map[string]yfa.PriceData) string {
-	var start, end time.Time
-	end = time.Now()
+	keys := validKeys(hist)
+	if len(keys) < 2 {
+		return "N/A"
+	}
+
+	endKey := keys[len(keys)-1]
+	end, err := yfa.ParseDateKey(endKey)
+	if err != nil {
+		return "N/A"
+	}
 
+	var start time.Time
 	switch period {
 	case "1wk":
 		start = end.AddDate(0, 0, -7)
@@ -48,32 +60,54 @@ func PeriodChange(period string, hist map[string]yfa.PriceData) string {
 		start = start.AddDate(0, 0, -2)
 	}
 
-	if end.Weekday() == time.Saturday {
-		end = end.AddDate(0, 0, -1)
-	} else if end.Weekday() == time.Sunday {
-		end = end.AddDate(0, 0, -2)
-	}
-
-	if _, ok := hist[start.Format("2006-01-02")]; !ok {
+	// holidays and null bars leave gaps, fall back to the last valid close before the date
+	startKey, ok := closestOnOrBefore(keys, start.Format("2006-01-02"))
+	if !ok || endKey == startKey {
 		return "N/A"
 	}
 
-	if _, ok := hist[end.Format("2006-01-02")]; !ok {
-		return "N/A"
-	}
+	startPrice := hist[startKey].Close
+	endPrice := hist[endKey].Close
+	percentChange := ((endPrice - startPrice) / startPrice) * 100
 
-	if len(hist) < 2 {
-		return "N/A"
+	return fmt.Sprintf("%.2f%%", percentChange)
+}
+
+// validKeys returns the sorted dates of the bars that carry a price
+func validKeys(hist map[string]yfa.PriceData) []string {
+	keys := make([]string, 0, len(hist))
+	for k, v := range hist {
+		if v.Valid() {
+			keys = append(keys, k)
+		}
 	}
+	slices.Sort(keys)
+	return keys
+}
 
-	startPrice := hist[start.Format("2006-01-02")].Close
-	endPrice := hist[end.Format("2006-01-02")].Close
-	percentChange := ((endPrice - startPrice) / startPrice) * 100
+// closestOnOrBefore returns the latest key on or before date, allowing up to a week of gap
+func closestOnOrBefore(keys []string, date string) (string, bool) {
+	i, found := slices.BinarySearch(keys, date)
+	if found {
+		return keys[i], true
+	}
+	if i == 0 {
+		return "", false
+	}
 
-	return fmt.Sprintf("%.2f%%", percentChange)
+	key := keys[i-1]
+	keyDate, err := yfa.ParseDateKey(key)
+	if err != nil {
+		return "", false
+	}
+	target, _ := yfa.ParseDateKey(date)
+	if target.Sub(keyDate) > 7*24*time.Hour {
+		return "", false
+	}
+	return key, true
 }
 
-func FetchHistory(ticker *yfa.Ticker, period string) (yfa.PriceHistory, error) {
+func FetchHistory(ctx context.Context, provider yfa.MarketDataProvider, symbol, period string) (yfa.PriceHistory, error) {
 	var start, end time.Time
 	end = time.Now()
 	interval := "1d"
@@ -111,14 +145,14 @@ func FetchHistory(ticker *yfa.Ticker, period string) (yfa.PriceHistory, error) {
 
 	var daily, yearly map[string]yfa.PriceData
 
-	rows, dbErr := database.GetStockPrices(ticker.Symbol, start, end)
+	rows, dbErr := database.GetStockPrices(ctx, symbol, start, end)
 	if dbErr == nil && len(rows) > 0 {
 		yearly = stockPriceToPriceData(rows)
 	}
 
 	// if DB miss, attempt to fetch yearly from yahoo
 	if yearly == nil {
-		if hist, hErr := ticker.History(yfa.HistoryQuery{
+		if hist, hErr := provider.History(ctx, symbol, yfa.HistoryQuery{
 			Start:    start.Format("2006-01-02"),
 			End:      fmt.Sprintf("%d", end.Unix()),
 			Interval: "1d",
@@ -128,7 +162,7 @@ func FetchHistory(ticker *yfa.Ticker, period string) (yfa.PriceHistory, error) {
 	}
 
 	if period == "1d" {
-		hist, err := ticker.History(yfa.HistoryQuery{
+		hist, err := provider.History(ctx, symbol, yfa.HistoryQuery{
 			Start:    start.Format("2006-01-02"),
 			End:      fmt.Sprintf("%d", end.Unix()),
 			Interval: interval, // "1m"
@@ -150,28 +184,23 @@ func FetchHistory(ticker *yfa.Ticker, period string) (yfa.PriceHistory, error) {
 func stockPriceToPriceData(rows []database.StockPrice) map[string]yfa.PriceData {
 	m := make(map[string]yfa.PriceData, len(rows))
 	for _, r := range rows {
-		key := r.Date.UTC().Format("2006-01-02")
+		if r.Close <= 0 {
+			continue
+		}
+		key := yfa.NormalizeDate(r.Date.UTC()).Format("2006-01-02")
 		m[key] = yfa.PriceData{
-			Open:   r.Open,
-			High:   r.High,
-			Low:    r.Low,
-			Close:  r.Close,
-			Volume: r.Volume,
+			Open:     r.Open,
+			High:     r.High,
+			Low:      r.Low,
+			Close:    r.Close,
+			AdjClose: r.AdjClose,
+			Volume:   r.Volume,
 		}
 	}
 	return m
 }
 
 func GenerateLineChart(hist map[string]yfa.PriceData, info yfa.YahooTickerInfo, period string) *discord.File {
-	t := time.Now()
-	tmp, err := os.CreateTemp(WORKING_DIR, fmt.Sprintf("chart-%d-*.png", t.UnixNano()))
-	if err != nil {
-		slog.Error("Error creating temp file", slog.Any("err", err))
-		return nil
-	}
-	tmpName := tmp.Name()
-	tmp.Close()
-
 	line := charts.NewLine()
 	line.SetGlobalOptions(
 		charts.WithInitializationOpts(opts.Initialization{
@@ -209,7 +238,7 @@ func GenerateLineChart(hist map[string]yfa.PriceData, info yfa.YahooTickerInfo,
 	var axes []string
 	var values []yfa.PriceData
 	for _, k := range keys {
-		if hist[k].Close == 0 {
+		if !hist[k].Valid() {
 			continue
 		}
 
@@ -231,7 +260,21 @@ func GenerateLineChart(hist map[string]yfa.PriceData, info yfa.YahooTickerInfo,
 			}),
 		)
 
-	err = render.MakeChartSnapshot(line.RenderContent(), tmpName)
+	return snapshotChart(line.RenderContent())
+}
+
+// snapshotChart renders the chart content into a png file attachment
+func snapshotChart(content []byte) *discord.File {
+	t := time.Now()
+	tmp, err := os.CreateTemp(WORKING_DIR, fmt.Sprintf("chart-%d-*.png", t.UnixNano()))
+	if err != nil {
+		slog.Error("Error creating temp file", slog.Any("err", err))
+		return nil
+	}
+	tmpName := tmp.Name()
+	tmp.Close()
+
+	err = render.MakeChartSnapshot(content, tmpName)
 	if err != nil {
 		slog.Error("Error rendering image", slog.Any("err", err))
 		os.Remove(tmpName)
@@ -261,6 +304,106 @@ func genLineData(symbol string, values []yfa.PriceData) (rs []opts.LineData) {
 	return
 }
 
+// ivStrikeWindow limits the IV chart to strikes within this fraction of the underlying price, far out strikes are mostly noise
+const ivStrikeWindow = 0.3
+
+// GenerateIVChart renders the implied volatility by strike of the calls and puts of a single expiration
+func GenerateIVChart(symbol string, chain yfa.OptionData, price float64) *discord.File {
+	line := charts.NewLine()
+	line.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{
+			BackgroundColor: "#FFFFFF",
+			Width:           "100%",
+		}),
+
+		charts.WithAnimation(false),
+		charts.WithTitleOpts(opts.Title{
+			Title: fmt.Sprintf("%s implied volatility %s", symbol, chain.ExpirationDate),
+			Right: "40%",
+		}),
+		charts.WithYAxisOpts(
+			opts.YAxis{
+				Name:         "IV (%)",
+				Position:     "left",
+				NameLocation: "middle",
+				NameGap:      35,
+				Scale:        opts.Bool(true),
+			},
+		),
+		charts.WithXAxisOpts(
+			opts.XAxis{
+				Name:         "Strike",
+				Position:     "bottom",
+				NameLocation: "center",
+				NameGap:      25,
+			},
+		),
+		charts.WithLegendOpts(opts.Legend{Show: opts.Bool(true)}),
+	)
+
+	inWindow := func(option yfa.OptionDetail) bool {
+		// Yahoo reports a near-zero IV for contracts without quotes
+		if option.ImpliedVolatility < 0.001 {
+			return false
+		}
+		return price <= 0 || (option.Strike >= price*(1-ivStrikeWindow) && option.Strike <= price*(1+ivStrikeWindow))
+	}
+
+	calls := make(map[float64]float64)
+	puts := make(map[float64]float64)
+	for _, call := range chain.Calls {
+		if inWindow(call) {
+			calls[call.Strike] = call.ImpliedVolatility * 100
+		}
+	}
+	for _, put := range chain.Puts {
+		if inWindow(put) {
+			puts[put.Strike] = put.ImpliedVolatility * 100
+		}
+	}
+
+	strikes := slices.Sorted(maps.Keys(calls))
+	for strike := range puts {
+		if _, ok := calls[strike]; !ok {
+			strikes = append(strikes, strike)
+		}
+	}
+	slices.Sort(strikes)
+	if len(strikes) == 0 {
+		return nil
+	}
+
+	axes := make([]string, 0, len(strikes))
+	for _, strike := range strikes {
+		axes = append(axes, fmt.Sprintf("%g", strike))
+	}
+
+	line.SetXAxis(axes).
+		AddSeries("Calls", genIVData(strikes, calls)).
+		AddSeries("Puts", genIVData(strikes, puts)).
+		SetSeriesOptions(
+			charts.WithLineChartOpts(opts.LineChart{
+				ShowSymbol:   opts.Bool(true),
+				ConnectNulls: opts.Bool(true),
+			}),
+		)
+
+	return snapshotChart(line.RenderContent())
+}
+
+// genIVData returns the IV per strike, strikes without a contract are left empty
+func genIVData(strikes []float64, ivs map[float64]float64) (rs []opts.LineData) {
+	rs = make([]opts.LineData, 0, len(strikes))
+	for _, strike := range strikes {
+		if iv, ok := ivs[strike]; ok {
+			rs = append(rs, opts.LineData{Value: fmt.Sprintf("%.2f", iv)})
+		} else {
+			rs = append(rs, opts.LineData{Value: "-"})
+		}
+	}
+	return
+}
+
 func periodToFriendlyName(period string) string {
 	switch period {
 	case "1d":
@@ -1,8 +1,12 @@
 package trackers
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/disgoorg/disgo/bot"
@@ -12,22 +16,60 @@ import (
 	"github.com/stollenaar/stockbot/internal/util/yfa"
 )
 
-func StartChecker(client *bot.Client) {
+const (
+	// alertCheckTimeout bounds a single pass over the watchlists
+	alertCheckTimeout = 30 * time.Second
+	// refreshTimeout bounds the daily refresh of all tracked stocks
+	refreshTimeout = 1 * time.Hour
+	// newsCheckInterval is how often the watched symbols are checked for new headlines
+	newsCheckInterval = 10 * time.Minute
+	// newsCheckTimeout bounds a single pass over the news watches
+	newsCheckTimeout = 2 * time.Minute
+	// refreshWindowDays is how far back the daily refresh fetches at least, to pick up corrections of the last bars
+	refreshWindowDays = 5
+)
+
+// StartChecker starts the alert checker and the daily refresh, both stop when ctx is cancelled.
+func StartChecker(ctx context.Context, client *bot.Client, provider yfa.MarketDataProvider) {
 	go func() {
 		ticker := time.NewTicker(5 * time.Second)
-		for range ticker.C {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
 			now := time.Now()
 			if now.Weekday() != time.Saturday && now.Weekday() != time.Sunday {
-				CheckAlerts(client)
+				checkCtx, cancel := context.WithTimeout(ctx, alertCheckTimeout)
+				CheckAlerts(checkCtx, client, provider)
+				cancel()
+			}
+		}
+	}()
+
+	// news is published on weekends too
+	go func() {
+		ticker := time.NewTicker(newsCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
 			}
+			checkCtx, cancel := context.WithTimeout(ctx, newsCheckTimeout)
+			CheckNews(checkCtx, client, provider)
+			cancel()
 		}
 	}()
 
-	scheduleDailyRefresh()
+	scheduleDailyRefresh(ctx, provider)
 }
 
 // scheduleDailyRefresh triggers once per day at 23:00 UTC.
-func scheduleDailyRefresh() {
+func scheduleDailyRefresh(ctx context.Context, provider yfa.MarketDataProvider) {
 	go func() {
 		for {
 			now := time.Now().UTC()
@@ -35,30 +77,41 @@ func scheduleDailyRefresh() {
 			if !target.After(now) {
 				target = target.Add(24 * time.Hour)
 			}
-			sleep := time.Until(target)
-			time.Sleep(sleep)
+			timer := time.NewTimer(time.Until(target))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
 
-			RefreshTrackedStocks()
+			refreshCtx, cancel := context.WithTimeout(ctx, refreshTimeout)
+			RefreshTrackedStocks(refreshCtx, provider)
+			cancel()
 		}
 	}()
 }
 
-func RefreshTrackedStocks() {
-	trackedStock, err := database.GetTrackedStocks()
+func RefreshTrackedStocks(ctx context.Context, provider yfa.MarketDataProvider) {
+	trackedStock, err := database.GetTrackedStocks(ctx)
 	if err != nil {
 		slog.Error("Error fetching tracked stocks:", slog.Any("err", err))
 		return
 	}
 
 	for _, symbol := range trackedStock {
-		ticker := yfa.NewTicker(symbol)
-
-		// fetch history for the current UTC day
+		// fetch from the latest stored bar, so the days and corporate actions missed while the bot was down are included
 		now := time.Now().UTC()
-		day := now.Format("2006-01-02")
-		hist, err := ticker.History(yfa.HistoryQuery{
-			Start:    day,
-			End:      day,
+		start := now.AddDate(0, 0, -refreshWindowDays)
+		if latest, ok, err := database.GetLatestStockPriceDate(ctx, symbol); err != nil {
+			slog.Error("Error fetching the latest stored price:", slog.Any("err", err), slog.String("symbol", symbol))
+		} else if ok && latest.Before(start) {
+			start = latest
+		}
+
+		chart, err := provider.Chart(ctx, symbol, yfa.HistoryQuery{
+			Start:    start.Format("2006-01-02"),
+			End:      fmt.Sprintf("%d", now.Unix()),
 			Interval: "1d",
 		})
 
@@ -67,54 +120,56 @@ func RefreshTrackedStocks() {
 			continue
 		}
 
-		var pd yfa.PriceData
-		var dateKey string
-		if v, ok := hist[day]; ok {
-			pd = v
-			dateKey = day
-		} else {
-			// fallback to the most recent available key in the returned map
-			for k := range hist {
-				if dateKey == "" || k > dateKey {
-					dateKey = k
-				}
+		// a split that isn't recorded yet rescales the stored history and the portfolio shares.
+		// The actions go first, the fetched bars are already split adjusted and mustn't be rescaled again.
+		if err := database.SetCorporateActions(ctx, database.CorporateActionsFromYahoo(symbol, chart.Actions), true); err != nil {
+			slog.Error("failed to set corporate actions", slog.Any("err", err), slog.String("symbol", symbol))
+			continue
+		}
+
+		var prices []database.StockPrice
+		for dateKey, pd := range chart.History {
+			if !pd.Valid() {
+				continue
 			}
-			if dateKey == "" {
-				// no data available
-				slog.Debug("no history rows returned", slog.String("symbol", symbol))
+			// the key of a bar is the exchange's trading day, stored as UTC
+			parsedDate, err := yfa.ParseDateKey(dateKey)
+			if err != nil {
 				continue
 			}
-			pd = hist[dateKey]
-		}
-
-		// parse the date key into a time.Time (stored as UTC)
-		parsedDate, err := time.ParseInLocation("2006-01-02", dateKey, time.UTC)
-		if err != nil {
-			// fallback to now UTC if parsing fails
-			parsedDate = now
+			prices = append(prices, database.StockPrice{
+				Symbol:   symbol,
+				Date:     parsedDate,
+				Open:     pd.Open,
+				High:     pd.High,
+				Low:      pd.Low,
+				Close:    pd.Close,
+				AdjClose: pd.AdjClose,
+				Volume:   pd.Volume,
+			})
 		}
-
-		// build StockPrice and persist
-		sp := database.StockPrice{
-			Symbol: symbol,
-			Date:   parsedDate,
-			Open:   pd.Open,
-			High:   pd.High,
-			Low:    pd.Low,
-			Close:  pd.Close,
-			Volume: int64(pd.Volume),
+		if len(prices) == 0 {
+			slog.Debug("no history rows returned", slog.String("symbol", symbol))
+			continue
 		}
 
-		if err := database.SetStockPrice(sp); err != nil {
-			slog.Error("failed to set stock price", slog.Any("err", err), slog.String("symbol", symbol), slog.Time("date", parsedDate))
+		if err := database.SetStockPrices(ctx, prices); err != nil {
+			slog.Error("failed to set stock prices", slog.Any("err", err), slog.String("symbol", symbol))
 			continue
 		}
 		time.Sleep(500 * time.Millisecond)
 	}
 }
 
-func CheckAlerts(client *bot.Client) {
-	watchlists, err := database.GetWatchLists()
+func CheckAlerts(ctx context.Context, client *bot.Client, provider yfa.MarketDataProvider) {
+	checkAlerts(ctx, provider, func(w database.WatchList, content string) error {
+		return notify(client, w, content)
+	})
+}
+
+// checkAlerts sends an alert for every watch whose target the latest price crossed and marks it as triggered
+func checkAlerts(ctx context.Context, provider yfa.MarketDataProvider, send func(w database.WatchList, content string) error) {
+	watchlists, err := database.GetWatchLists(ctx)
 
 	if err != nil {
 		slog.Error("Error fetching watchlists:", slog.Any("err", err))
@@ -126,40 +181,126 @@ func CheckAlerts(client *bot.Client) {
 		grouped[watched.Symbol] = append(grouped[watched.Symbol], watched)
 	}
 
-	for symbol, lists := range grouped {
-		ticker := yfa.NewTicker(symbol)
-		// get the latest PriceData
-		info, err := ticker.Info()
+	if len(grouped) == 0 {
+		return
+	}
 
-		if err != nil {
+	// get the latest quotes for every watched symbol in one go
+	infos, err := provider.Quotes(ctx, slices.Collect(maps.Keys(grouped)))
+	if err != nil {
+		slog.Error("Error fetching quotes:", slog.Any("err", err))
+		return
+	}
+
+	for symbol, lists := range grouped {
+		info, ok := infos[symbol]
+		if !ok || info.RegularMarketPrice == nil {
 			continue
 		}
 
-		toMention := make(map[bool][]string)
 		for _, w := range lists {
+			var content string
 			if w.Direction && info.RegularMarketPrice.Raw >= w.PriceTarget {
-				toMention[true] = append(toMention[true], w.UserID)
-				flk, err := snowflake.Parse(w.UserID)
-				if err != nil {
+				content = fmt.Sprintf("This is a price alert for %s\nThe current price is %s which is above your target of %.2f", w.Symbol, info.RegularMarketPrice.Fmt, w.PriceTarget)
+			} else if !w.Direction && info.RegularMarketPrice.Raw <= w.PriceTarget {
+				content = fmt.Sprintf("This is a price alert for %s\nThe current price is %s which is below your target of %.2f", w.Symbol, info.RegularMarketPrice.Fmt, w.PriceTarget)
+			} else {
+				continue
+			}
+
+			if err := send(w, content); err != nil {
+				// left untriggered, so the alert is sent again on the next check
+				slog.Error("Error sending alert:", slog.Any("err", err), slog.String("user", w.UserID))
+				continue
+			}
+			if err := w.SetTriggerWatchlist(ctx); err != nil {
+				slog.Error("Error marking alert as triggered:", slog.Any("err", err), slog.String("user", w.UserID))
+			}
+		}
+	}
+}
+
+// CheckNews DMs the headlines published since a user turned on news for a watched symbol.
+// Sent headlines are recorded so they are never sent to the same user twice.
+func CheckNews(ctx context.Context, client *bot.Client, provider yfa.MarketDataProvider) {
+	watchlists, err := database.GetNewsWatchLists(ctx)
+	if err != nil {
+		slog.Error("Error fetching news watchlists:", slog.Any("err", err))
+		return
+	}
+
+	grouped := make(map[string][]database.WatchList)
+	for _, watched := range watchlists {
+		grouped[watched.Symbol] = append(grouped[watched.Symbol], watched)
+	}
+
+	for symbol, lists := range grouped {
+		news, err := provider.News(ctx, symbol)
+		if err != nil {
+			slog.Error("Error fetching news:", slog.Any("err", err), slog.String("symbol", symbol))
+			continue
+		}
+
+		// oldest first, so the DMs read in order
+		for i := len(news) - 1; i >= 0; i-- {
+			item := news[i]
+			for _, w := range lists {
+				if item.Published.Before(*w.NewsSince) {
 					continue
 				}
-				dmChannel, _ := client.Rest.CreateDMChannel(flk)
-				client.Rest.CreateMessage(dmChannel.ID(), discord.MessageCreate{
-					Content: fmt.Sprintf("This is a price alert for %s\nThe current price is %s which is above your target of %.2f", w.Symbol, info.RegularMarketPrice.Fmt, w.PriceTarget),
-				})
-				w.SetTriggerWatchlist()
-			} else if !w.Direction && info.RegularMarketPrice.Raw <= w.PriceTarget {
-				toMention[false] = append(toMention[false], w.UserID)
-				flk, err := snowflake.Parse(w.UserID)
+				claimed, err := database.ClaimNews(ctx, w.UserID, symbol, item.ID)
 				if err != nil {
+					slog.Error("Error recording sent news:", slog.Any("err", err), slog.String("symbol", symbol))
 					continue
 				}
-				dmChannel, _ := client.Rest.CreateDMChannel(flk)
-				client.Rest.CreateMessage(dmChannel.ID(), discord.MessageCreate{
-					Content: fmt.Sprintf("This is a price alert for %s\nThe current price is %s which is below your target of %.2f", w.Symbol, info.RegularMarketPrice.Fmt, w.PriceTarget),
-				})
-				w.SetTriggerWatchlist()
+				if !claimed {
+					continue
+				}
+
+				if err := notify(client, w, fmt.Sprintf("News for %s\n%s\n-# %s", symbol, FormatHeadline(item), item.Publisher)); err != nil {
+					slog.Error("Error sending news:", slog.Any("err", err), slog.String("user", w.UserID))
+					if err := database.ReleaseNews(ctx, w.UserID, item.ID); err != nil {
+						slog.Error("Error releasing news:", slog.Any("err", err))
+					}
+				}
 			}
 		}
 	}
 }
+
+// notify DMs the owner of the watch, a guild watch posts in its channel instead
+func notify(client *bot.Client, w database.WatchList, content string) error {
+	var channelID snowflake.ID
+	if w.ChannelID != "" {
+		id, err := snowflake.Parse(w.ChannelID)
+		if err != nil {
+			return err
+		}
+		channelID = id
+	} else {
+		userID, err := snowflake.Parse(w.UserID)
+		if err != nil {
+			return err
+		}
+		dmChannel, err := client.Rest.CreateDMChannel(userID)
+		if err != nil {
+			return err
+		}
+		channelID = dmChannel.ID()
+	}
+
+	_, err := client.Rest.CreateMessage(channelID, discord.MessageCreate{
+		Content: content,
+	})
+	return err
+}
+
+// FormatHeadline renders a headline as a link with its publish time
+func FormatHeadline(item yfa.NewsItem) string {
+	// brackets in the title would end the markdown link early
+	title := strings.NewReplacer("[", "(", "]", ")").Replace(item.Title)
+	if item.Link == "" {
+		return fmt.Sprintf("**%s** <t:%d:R>", title, item.Published.Unix())
+	}
+	return fmt.Sprintf("**[%s](<%s>)** <t:%d:R>", title, item.Link, item.Published.Unix())
+}
@@ -0,0 +1,261 @@
+package trackers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stollenaar/stockbot/internal/database"
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+	"github.com/stollenaar/stockbot/internal/util/yfa/fakeyahoo"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "stockbot-trackers")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := database.Open(dir); err != nil {
+		log.Fatal(err)
+	}
+	code := m.Run()
+	database.Exit()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// startScenario seeds a fake Yahoo with the scenario in testdata and points yfa and the database at it.
+// The steps are played by the test, so it controls when the scripted changes happen.
+func startScenario(t *testing.T, name string) (*fakeyahoo.Fake, fakeyahoo.Scenario, yfa.MarketDataProvider) {
+	t.Helper()
+	scenario, err := fakeyahoo.LoadScenario("testdata/" + name)
+	if err != nil {
+		t.Fatalf("LoadScenario: %v", err)
+	}
+
+	fake := fakeyahoo.New()
+	fake.Seed(scenario)
+	srv := fake.Start()
+	t.Cleanup(srv.Close)
+
+	baseURL, cookieURL := yfa.BASE_URL, yfa.COOKIE_URL
+	t.Cleanup(func() { yfa.BASE_URL, yfa.COOKIE_URL = baseURL, cookieURL })
+	yfa.SetBaseURL(srv.URL)
+	yfa.ConfigureSharedClient(
+		yfa.WithRetryConfig(yfa.RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}),
+		yfa.WithRateLimit(yfa.RateLimit{Rate: 1000, Burst: 1000}, nil),
+	)
+
+	provider := yfa.NewYahooProvider()
+	database.SetProvider(provider)
+	return fake, scenario, provider
+}
+
+type sentAlert struct {
+	userID  string
+	content string
+}
+
+func TestCheckAlertsAfterPriceMove(t *testing.T) {
+	fake, scenario, provider := startScenario(t, "price_move.json")
+	ctx := context.Background()
+
+	watches := []database.WatchList{
+		{UserID: "1", Symbol: "ACME", PriceTarget: 110, Direction: true},
+		{UserID: "2", Symbol: "ACME", PriceTarget: 90, Direction: false},
+		{UserID: "3", Symbol: "ACME", PriceTarget: 150, Direction: true},
+	}
+	for _, w := range watches {
+		if err := w.UpsertWatchlist(ctx); err != nil {
+			t.Fatalf("UpsertWatchlist: %v", err)
+		}
+	}
+
+	var sent []sentAlert
+	send := func(w database.WatchList, content string) error {
+		sent = append(sent, sentAlert{userID: w.UserID, content: content})
+		return nil
+	}
+
+	checkAlerts(ctx, provider, send)
+	if len(sent) != 0 {
+		t.Fatalf("alerts before the move = %+v, want none", sent)
+	}
+
+	// ACME moves 12% up to 112, crossing the target of user 1 only
+	if err := fake.Play(ctx, scenario); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+
+	// an alert that couldn't be delivered isn't marked as triggered
+	failed := 0
+	checkAlerts(ctx, provider, func(w database.WatchList, content string) error {
+		failed++
+		return errors.New("cannot send messages to this user")
+	})
+	if failed != 1 {
+		t.Fatalf("attempted %d alerts, want 1", failed)
+	}
+
+	checkAlerts(ctx, provider, send)
+	if len(sent) != 1 || sent[0].userID != "1" {
+		t.Fatalf("alerts after the move = %+v, want one for user 1", sent)
+	}
+	if !strings.Contains(sent[0].content, "112.00 which is above your target of 110.00") {
+		t.Errorf("alert = %q, want the price and target", sent[0].content)
+	}
+
+	pending, err := database.GetWatchLists(ctx)
+	if err != nil {
+		t.Fatalf("GetWatchLists: %v", err)
+	}
+	for _, w := range pending {
+		if w.UserID == "1" && w.Symbol == "ACME" {
+			t.Error("the alert of user 1 wasn't marked as triggered")
+		}
+	}
+	if len(pending) != 2 {
+		t.Errorf("pending watches = %+v, want the ones of user 2 and 3", pending)
+	}
+
+	// a triggered alert isn't sent again
+	checkAlerts(ctx, provider, send)
+	if len(sent) != 1 {
+		t.Errorf("alerts after a second check = %+v, want still one", sent)
+	}
+}
+
+func TestHistorySkipsNullBars(t *testing.T) {
+	fake, scenario, _ := startScenario(t, "null_bars.json")
+	ctx := context.Background()
+	query := yfa.HistoryQuery{Range: "1mo", Interval: "1d"}
+
+	before, err := yfa.NewTicker("GAPS").History(ctx, query)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+
+	// the three sessions before the current one turn into null bars
+	if err := fake.Play(ctx, scenario); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	after, err := yfa.NewTicker("GAPS").History(ctx, query)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+
+	if len(after) != len(before)-3 {
+		t.Errorf("history has %d bars after the nulls, want %d", len(after), len(before)-3)
+	}
+	for date, bar := range after {
+		if !bar.Valid() {
+			t.Errorf("%s = %+v, want only bars with a price", date, bar)
+		}
+	}
+}
+
+func TestThrottleScenario(t *testing.T) {
+	fake, scenario, _ := startScenario(t, "throttle.json")
+	ctx := context.Background()
+	query := yfa.HistoryQuery{Range: "5d", Interval: "1d"}
+
+	// three 429s use up the initial attempt and both retries
+	if err := fake.Play(ctx, scenario); err != nil {
+		t.Fatalf("Play: %v", err)
+	}
+	_, err := yfa.NewTicker("BUSY").History(ctx, query)
+	var throttled *yfa.ThrottledError
+	if !errors.As(err, &throttled) || throttled.Endpoint != "chart" {
+		t.Fatalf("err = %v, want a ThrottledError for the chart endpoint", err)
+	}
+	if got := fake.Requests()["chart"]; got != 3 {
+		t.Errorf("chart requests = %d, want 3", got)
+	}
+
+	// once the throttle is over the next request goes through
+	history, err := yfa.NewTicker("BUSY").History(ctx, query)
+	if err != nil {
+		t.Fatalf("History after the throttle: %v", err)
+	}
+	if len(history) == 0 {
+		t.Error("history after the throttle is empty")
+	}
+}
+
+// splitProvider answers Chart for one symbol with post-split bars from the start of the query and a 2:1 split
+type splitProvider struct {
+	yfa.MarketDataProvider
+	symbol string
+	split  time.Time
+	starts []string
+}
+
+func (p *splitProvider) Chart(ctx context.Context, symbol string, query yfa.HistoryQuery) (yfa.ChartData, error) {
+	if symbol != p.symbol {
+		return yfa.ChartData{}, &yfa.UnknownSymbolError{Symbol: symbol}
+	}
+	p.starts = append(p.starts, query.Start)
+
+	start, err := time.Parse("2006-01-02", query.Start)
+	if err != nil {
+		return yfa.ChartData{}, err
+	}
+	history := make(map[string]yfa.PriceData)
+	for day := start; !day.After(time.Now()); day = day.AddDate(0, 0, 1) {
+		history[day.Format("2006-01-02")] = yfa.PriceData{Open: 50, High: 50, Low: 50, Close: 50, AdjClose: 50, Volume: 1000}
+	}
+	split := yfa.CorporateAction{Date: p.split.Format("2006-01-02"), Type: yfa.ActionSplit, Numerator: 2, Denominator: 1}
+	return yfa.ChartData{History: history, Actions: []yfa.CorporateAction{split}}, nil
+}
+
+func TestRefreshAfterDowntime(t *testing.T) {
+	startScenario(t, "price_move.json")
+	ctx := context.Background()
+	if err := database.AddTrackedStock(ctx, "ACME"); err != nil {
+		t.Fatalf("AddTrackedStock: %v", err)
+	}
+
+	// the bot was down for the last three weeks, a split happened in between
+	today := yfa.NormalizeDate(time.Now().UTC())
+	for day := today.AddDate(0, 0, -21); !day.After(today); day = day.AddDate(0, 0, 1) {
+		if err := database.RemoveStockPrice(ctx, "ACME", day); err != nil {
+			t.Fatalf("RemoveStockPrice: %v", err)
+		}
+	}
+	latest, ok, err := database.GetLatestStockPriceDate(ctx, "ACME")
+	if err != nil || !ok {
+		t.Fatalf("GetLatestStockPriceDate = %v, %v", ok, err)
+	}
+	older := today.AddDate(0, -2, 0)
+	before, err := database.GetStockPrices(ctx, "ACME", older.AddDate(0, 0, -7), older)
+	if err != nil || len(before) == 0 {
+		t.Fatalf("GetStockPrices = %v, %v", before, err)
+	}
+
+	provider := &splitProvider{symbol: "ACME", split: today.AddDate(0, 0, -10)}
+	RefreshTrackedStocks(ctx, provider)
+
+	if len(provider.starts) != 1 || provider.starts[0] != latest.Format("2006-01-02") {
+		t.Fatalf("chart starts = %v, want the latest stored bar of %s", provider.starts, latest.Format("2006-01-02"))
+	}
+
+	// the history stored before the downtime is rescaled, the missed days are filled in
+	after, err := database.GetStockPrices(ctx, "ACME", older.AddDate(0, 0, -7), older)
+	if err != nil {
+		t.Fatalf("GetStockPrices: %v", err)
+	}
+	if len(after) != len(before) || after[0].Close != before[0].Close/2 {
+		t.Errorf("close before the split = %v, want %v halved", after[0].Close, before[0].Close)
+	}
+	gap, err := database.GetStockPrices(ctx, "ACME", today.AddDate(0, 0, -21), today)
+	if err != nil {
+		t.Fatalf("GetStockPrices: %v", err)
+	}
+	if len(gap) != 22 || gap[0].Close != 50 {
+		t.Errorf("stored %d bars in the downtime, want all 22 at the split adjusted 50", len(gap))
+	}
+}
@@ -0,0 +1,289 @@
+package yfa
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheTTL holds how long a cached response stays fresh per endpoint
+type CacheTTL struct {
+	// Quote is used for Quote, Info and Quotes
+	Quote time.Duration
+	// Fundamentals is used for the quoteSummary fundamentals modules
+	Fundamentals time.Duration
+	// IntradayHistory is used for History with minute or hour intervals
+	IntradayHistory time.Duration
+	// DailyHistory is used for History with day, week or month intervals
+	DailyHistory time.Duration
+	// Options is used for the option chains and expiration dates
+	Options time.Duration
+	// Search is used for symbol searches, which autocomplete repeats on every keystroke
+	Search time.Duration
+	// News is used for the headlines of a symbol
+	News time.Duration
+}
+
+// DefaultCacheTTL keeps live quotes short and daily history long
+var DefaultCacheTTL = CacheTTL{
+	Quote:           15 * time.Second,
+	Fundamentals:    1 * time.Hour,
+	IntradayHistory: 1 * time.Minute,
+	DailyHistory:    1 * time.Hour,
+	Options:         1 * time.Minute,
+	Search:          1 * time.Hour,
+	News:            5 * time.Minute,
+}
+
+// CacheFetchTimeout bounds a fetch shared by concurrent callers, which runs on after the caller that started it gave up
+var CacheFetchTimeout = 2 * time.Minute
+
+// CacheStats holds the hit/miss counters of a single endpoint
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+}
+
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+// flightCall is an in-flight request that concurrent identical requests wait on
+type flightCall struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+// CachedProvider wraps a MarketDataProvider with an in-process TTL cache.
+// Concurrent identical requests are deduplicated so only one reaches the wrapped provider.
+type CachedProvider struct {
+	next MarketDataProvider
+	ttl  CacheTTL
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	flights map[string]*flightCall
+	stats   map[string]*CacheStats
+}
+
+// NewCachedProvider creates a caching MarketDataProvider in front of next
+func NewCachedProvider(next MarketDataProvider, ttl CacheTTL) *CachedProvider {
+	return &CachedProvider{
+		next:    next,
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+		flights: make(map[string]*flightCall),
+		stats:   make(map[string]*CacheStats),
+	}
+}
+
+// CacheStats returns a snapshot of the hit/miss counters per endpoint
+func (c *CachedProvider) CacheStats() map[string]CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	snapshot := make(map[string]CacheStats, len(c.stats))
+	for endpoint, stats := range c.stats {
+		snapshot[endpoint] = *stats
+	}
+	return snapshot
+}
+
+// do returns the cached value for key, or calls fetch once for all concurrent callers and caches its result.
+// The fetch runs detached from ctx, bounded by CacheFetchTimeout, so a caller giving up doesn't fail the others waiting on it.
+// Every caller stops waiting when its own ctx is done.
+func (c *CachedProvider) do(ctx context.Context, endpoint, key string, ttl time.Duration, fetch func(ctx context.Context) (any, error)) (any, error) {
+	c.mu.Lock()
+	stats, ok := c.stats[endpoint]
+	if !ok {
+		stats = &CacheStats{}
+		c.stats[endpoint] = stats
+	}
+
+	if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expires) {
+		stats.Hits++
+		c.mu.Unlock()
+		return entry.value, nil
+	}
+	stats.Misses++
+
+	call, ok := c.flights[key]
+	if !ok {
+		call = &flightCall{done: make(chan struct{})}
+		c.flights[key] = call
+		go c.fetch(context.WithoutCancel(ctx), key, ttl, call, fetch)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.done:
+		return call.value, call.err
+	}
+}
+
+// fetch runs the in-flight call, caches a successful result and wakes up the callers waiting on it
+func (c *CachedProvider) fetch(ctx context.Context, key string, ttl time.Duration, call *flightCall, fetch func(ctx context.Context) (any, error)) {
+	ctx, cancel := context.WithTimeout(ctx, CacheFetchTimeout)
+	defer cancel()
+
+	call.value, call.err = fetch(ctx)
+
+	c.mu.Lock()
+	delete(c.flights, key)
+	if call.err == nil {
+		c.evictExpired()
+		c.entries[key] = cacheEntry{value: call.value, expires: time.Now().Add(ttl)}
+	}
+	c.mu.Unlock()
+	close(call.done)
+}
+
+// evictExpired drops stale entries once the cache grows, the caller must hold c.mu
+func (c *CachedProvider) evictExpired() {
+	if len(c.entries) < 1024 {
+		return
+	}
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *CachedProvider) Quote(ctx context.Context, symbol string) (PriceData, error) {
+	v, err := c.do(ctx, "quote", "quote:"+symbol, c.ttl.Quote, func(ctx context.Context) (any, error) {
+		return c.next.Quote(ctx, symbol)
+	})
+	if err != nil {
+		return PriceData{}, err
+	}
+	return v.(PriceData), nil
+}
+
+func (c *CachedProvider) Info(ctx context.Context, symbol string) (YahooTickerInfo, error) {
+	v, err := c.do(ctx, "info", "info:"+symbol, c.ttl.Quote, func(ctx context.Context) (any, error) {
+		return c.next.Info(ctx, symbol)
+	})
+	if err != nil {
+		return YahooTickerInfo{}, err
+	}
+	return v.(YahooTickerInfo), nil
+}
+
+func (c *CachedProvider) Fundamentals(ctx context.Context, symbol string) (Fundamentals, error) {
+	v, err := c.do(ctx, "fundamentals", "fundamentals:"+symbol, c.ttl.Fundamentals, func(ctx context.Context) (any, error) {
+		return c.next.Fundamentals(ctx, symbol)
+	})
+	if err != nil {
+		return Fundamentals{}, err
+	}
+	return v.(Fundamentals), nil
+}
+
+func (c *CachedProvider) Quotes(ctx context.Context, symbols []string) (map[string]YahooTickerInfo, error) {
+	v, err := c.do(ctx, "quotes", "quotes:"+strings.Join(symbols, ","), c.ttl.Quote, func(ctx context.Context) (any, error) {
+		return c.next.Quotes(ctx, symbols)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return maps.Clone(v.(map[string]YahooTickerInfo)), nil
+}
+
+// historyKey returns the cache key and ttl for a history query
+func (c *CachedProvider) historyKey(prefix, symbol string, query HistoryQuery) (string, time.Duration) {
+	ttl := c.ttl.DailyHistory
+	if strings.HasSuffix(query.Interval, "m") || strings.HasSuffix(query.Interval, "h") {
+		ttl = c.ttl.IntradayHistory
+	}
+
+	// End is usually "now", bucket it by the ttl so repeated requests share a key
+	end := query.End
+	if seconds, err := strconv.ParseInt(end, 10, 64); err == nil && ttl >= time.Second {
+		end = strconv.FormatInt(seconds/int64(ttl.Seconds()), 10)
+	}
+	return fmt.Sprintf("%s:%s:%s:%s:%s:%s", prefix, symbol, query.Range, query.Interval, query.Start, end), ttl
+}
+
+func (c *CachedProvider) History(ctx context.Context, symbol string, query HistoryQuery) (map[string]PriceData, error) {
+	key, ttl := c.historyKey("history", symbol, query)
+	v, err := c.do(ctx, "history", key, ttl, func(ctx context.Context) (any, error) {
+		return c.next.History(ctx, symbol, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return maps.Clone(v.(map[string]PriceData)), nil
+}
+
+func (c *CachedProvider) Chart(ctx context.Context, symbol string, query HistoryQuery) (ChartData, error) {
+	key, ttl := c.historyKey("chart", symbol, query)
+	v, err := c.do(ctx, "chart", key, ttl, func(ctx context.Context) (any, error) {
+		return c.next.Chart(ctx, symbol, query)
+	})
+	if err != nil {
+		return ChartData{}, err
+	}
+	chart := v.(ChartData)
+	return ChartData{History: maps.Clone(chart.History), Actions: slices.Clone(chart.Actions)}, nil
+}
+
+func (c *CachedProvider) OptionChain(ctx context.Context, symbol string) (OptionData, error) {
+	v, err := c.do(ctx, "options", "options:"+symbol, c.ttl.Options, func(ctx context.Context) (any, error) {
+		return c.next.OptionChain(ctx, symbol)
+	})
+	if err != nil {
+		return OptionData{}, err
+	}
+	return v.(OptionData), nil
+}
+
+func (c *CachedProvider) OptionChainByExpiration(ctx context.Context, symbol, expiration string) (OptionData, error) {
+	v, err := c.do(ctx, "options", "options:"+symbol+":"+expiration, c.ttl.Options, func(ctx context.Context) (any, error) {
+		return c.next.OptionChainByExpiration(ctx, symbol, expiration)
+	})
+	if err != nil {
+		return OptionData{}, err
+	}
+	return v.(OptionData), nil
+}
+
+func (c *CachedProvider) ExpirationDates(ctx context.Context, symbol string) ([]string, error) {
+	v, err := c.do(ctx, "expirations", "expirations:"+symbol, c.ttl.Options, func(ctx context.Context) (any, error) {
+		return c.next.ExpirationDates(ctx, symbol)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(v.([]string)), nil
+}
+
+func (c *CachedProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	v, err := c.do(ctx, "search", "search:"+strings.ToLower(query), c.ttl.Search, func(ctx context.Context) (any, error) {
+		return c.next.Search(ctx, query)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(v.([]SearchResult)), nil
+}
+
+func (c *CachedProvider) News(ctx context.Context, symbol string) ([]NewsItem, error) {
+	v, err := c.do(ctx, "news", "news:"+symbol, c.ttl.News, func(ctx context.Context) (any, error) {
+		return c.next.News(ctx, symbol)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(v.([]NewsItem)), nil
+}
@@ -0,0 +1,183 @@
+package yfa
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingProvider counts the quotes that reach it, blocking each one until release is closed when set
+type countingProvider struct {
+	MarketDataProvider
+	calls   atomic.Int32
+	release chan struct{}
+	err     error
+}
+
+func (p *countingProvider) Quote(ctx context.Context, symbol string) (PriceData, error) {
+	p.calls.Add(1)
+	if p.release != nil {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return PriceData{}, ctx.Err()
+		}
+	}
+	if p.err != nil {
+		return PriceData{}, p.err
+	}
+	return PriceData{Close: 100}, nil
+}
+
+func TestCachedProviderHitsAndMisses(t *testing.T) {
+	next := &countingProvider{}
+	cache := NewCachedProvider(next, CacheTTL{Quote: time.Minute})
+	ctx := context.Background()
+
+	for range 3 {
+		quote, err := cache.Quote(ctx, "AAPL")
+		if err != nil {
+			t.Fatalf("Quote: %v", err)
+		}
+		if quote.Close != 100 {
+			t.Errorf("Close = %v, want 100", quote.Close)
+		}
+	}
+	if _, err := cache.Quote(ctx, "MSFT"); err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+
+	if got := next.calls.Load(); got != 2 {
+		t.Errorf("provider calls = %d, want 2", got)
+	}
+	want := CacheStats{Hits: 2, Misses: 2}
+	if got := cache.CacheStats()["quote"]; got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestCachedProviderTTLExpiry(t *testing.T) {
+	next := &countingProvider{}
+	cache := NewCachedProvider(next, CacheTTL{Quote: 20 * time.Millisecond})
+	ctx := context.Background()
+
+	if _, err := cache.Quote(ctx, "AAPL"); err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	if _, err := cache.Quote(ctx, "AAPL"); err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	if got := next.calls.Load(); got != 1 {
+		t.Fatalf("provider calls before expiry = %d, want 1", got)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if _, err := cache.Quote(ctx, "AAPL"); err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	if got := next.calls.Load(); got != 2 {
+		t.Errorf("provider calls after expiry = %d, want 2", got)
+	}
+}
+
+func TestCachedProviderDoesNotCacheErrors(t *testing.T) {
+	next := &countingProvider{err: errors.New("boom")}
+	cache := NewCachedProvider(next, CacheTTL{Quote: time.Minute})
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := cache.Quote(ctx, "AAPL"); err == nil {
+			t.Fatal("Quote: want an error")
+		}
+	}
+	if got := next.calls.Load(); got != 2 {
+		t.Errorf("provider calls = %d, want 2", got)
+	}
+}
+
+func TestCachedProviderDeduplicates(t *testing.T) {
+	next := &countingProvider{release: make(chan struct{})}
+	cache := NewCachedProvider(next, CacheTTL{Quote: time.Minute})
+	ctx := context.Background()
+
+	const callers = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.Quote(ctx, "AAPL")
+			errs <- err
+		}()
+	}
+
+	// wait until every caller joined the flight before letting the fetch finish
+	waitFor(t, func() bool { return cache.CacheStats()["quote"].Misses == callers })
+	close(next.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Quote: %v", err)
+		}
+	}
+	if got := next.calls.Load(); got != 1 {
+		t.Errorf("provider calls = %d, want 1", got)
+	}
+}
+
+func TestCachedProviderCancelledCaller(t *testing.T) {
+	next := &countingProvider{release: make(chan struct{})}
+	cache := NewCachedProvider(next, CacheTTL{Quote: time.Minute})
+
+	// the caller that starts the fetch gives up, the one waiting on it still gets the result
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := make(chan error, 1)
+	go func() {
+		_, err := cache.Quote(ctx, "AAPL")
+		leader <- err
+	}()
+	waitFor(t, func() bool { return next.calls.Load() == 1 })
+
+	waiter := make(chan error, 1)
+	go func() {
+		_, err := cache.Quote(context.Background(), "AAPL")
+		waiter <- err
+	}()
+	waitFor(t, func() bool { return cache.CacheStats()["quote"].Misses == 2 })
+
+	cancel()
+	if err := <-leader; !errors.Is(err, context.Canceled) {
+		t.Errorf("leader err = %v, want context.Canceled", err)
+	}
+
+	close(next.release)
+	if err := <-waiter; err != nil {
+		t.Errorf("waiter err = %v, want nil", err)
+	}
+
+	// the detached fetch was cached
+	if _, err := cache.Quote(context.Background(), "AAPL"); err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+	if got := next.calls.Load(); got != 1 {
+		t.Errorf("provider calls = %d, want 1", got)
+	}
+}
+
+// waitFor polls cond until it holds or the test times out
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,194 @@
+package yfa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sync"
+)
+
+// CassetteMode selects whether a Recorder stores live responses or plays stored ones back
+type CassetteMode string
+
+const (
+	// CassetteRecord sends requests to Yahoo and stores every response in the cassette
+	CassetteRecord CassetteMode = "record"
+	// CassetteReplay answers requests from the cassette without touching the network
+	CassetteReplay CassetteMode = "replay"
+)
+
+// DefaultIgnoredParams are left out when matching a request to a recorded one.
+// The crumb is per session and the chart range is derived from the current time.
+var DefaultIgnoredParams = []string{"crumb", "period1", "period2"}
+
+// Interaction is a single recorded request and its response
+type Interaction struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// Cassette holds the recorded interactions in the order they happened
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper that records responses to a cassette file or replays them from it.
+// Identical requests are replayed in the order they were recorded, so a 429 followed by a 200 plays back the same way.
+type Recorder struct {
+	// IgnoredParams are the query parameters left out when matching requests
+	IgnoredParams []string
+
+	mode     CassetteMode
+	path     string
+	next     http.RoundTripper
+	mu       sync.Mutex
+	cassette Cassette
+	played   map[string]int
+}
+
+// NewRecorder creates a Recorder for the cassette at path.
+// In replay mode the cassette is loaded right away, in record mode requests are sent through next.
+func NewRecorder(path string, mode CassetteMode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{
+		IgnoredParams: DefaultIgnoredParams,
+		mode:          mode,
+		path:          path,
+		next:          next,
+		played:        make(map[string]int),
+	}
+
+	switch mode {
+	case CassetteRecord:
+	case CassetteReplay:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("failed to decode cassette %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown cassette mode: %q", mode)
+	}
+	return r, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == CassetteReplay {
+		return r.replay(req)
+	}
+	return r.record(req)
+}
+
+// Save writes the recorded interactions to the cassette file
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.save()
+}
+
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, data, 0644)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	// session cookies aren't needed to replay and shouldn't end up in a fixture
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Method: req.Method,
+		URL:    r.matchKey(req.URL),
+		Status: resp.StatusCode,
+		Header: header,
+		Body:   string(body),
+	})
+	// saved on every response so an interrupted run still leaves a usable cassette
+	if err := r.save(); err != nil {
+		return nil, fmt.Errorf("failed to save cassette: %w", err)
+	}
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	key := r.matchKey(req.URL)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matches []Interaction
+	for _, interaction := range r.cassette.Interactions {
+		if interaction.Method == req.Method && interaction.URL == key {
+			matches = append(matches, interaction)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, key)
+	}
+
+	// once every recording has been played the last one keeps answering
+	index := min(r.played[req.Method+" "+key], len(matches)-1)
+	r.played[req.Method+" "+key]++
+	interaction := matches[index]
+
+	header := interaction.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Status, http.StatusText(interaction.Status)),
+		StatusCode:    interaction.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewBufferString(interaction.Body)),
+		ContentLength: int64(len(interaction.Body)),
+		Request:       req,
+	}, nil
+}
+
+// matchKey returns the URL without the ignored query parameters, with the rest in a stable order.
+// The client always appends a '?', which is dropped when no parameters are left.
+func (r *Recorder) matchKey(u *url.URL) string {
+	query := u.Query()
+	for param := range query {
+		if slices.Contains(r.IgnoredParams, param) {
+			query.Del(param)
+		}
+	}
+	key := *u
+	key.RawQuery = query.Encode()
+	key.ForceQuery = false
+	key.Fragment = ""
+	return key.String()
+}
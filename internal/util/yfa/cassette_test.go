@@ -0,0 +1,185 @@
+package yfa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+// replayCassette answers every request of the shared client from the cassette in testdata/cassettes
+func replayCassette(t *testing.T, name string) {
+	t.Helper()
+	recorder, err := NewRecorder(filepath.Join("testdata", "cassettes", name), CassetteReplay, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	useClient(t, WithTransport(recorder))
+}
+
+func TestMatchKeyIgnoresSessionParams(t *testing.T) {
+	recorder := &Recorder{IgnoredParams: DefaultIgnoredParams}
+	key := func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil {
+			t.Fatalf("url.Parse: %v", err)
+		}
+		return recorder.matchKey(u)
+	}
+
+	want := "https://query2.finance.yahoo.com/v8/finance/chart/AAPL?events=div%2Csplits&interval=1d"
+	tests := []string{
+		"https://query2.finance.yahoo.com/v8/finance/chart/AAPL?interval=1d&events=div%2Csplits",
+		"https://query2.finance.yahoo.com/v8/finance/chart/AAPL?interval=1d&events=div%2Csplits&crumb=",
+		"https://query2.finance.yahoo.com/v8/finance/chart/AAPL?crumb=abc&interval=1d&period1=1&period2=2&events=div%2Csplits",
+		"https://query2.finance.yahoo.com/v8/finance/chart/AAPL?period2=1728676801&events=div%2Csplits&crumb=Xk3vR9pL2aQ&interval=1d#top",
+	}
+	for _, raw := range tests {
+		if got := key(raw); got != want {
+			t.Errorf("matchKey(%s) = %s, want %s", raw, got, want)
+		}
+	}
+
+	if got := key("https://query2.finance.yahoo.com/v7/finance/options/AAPL?crumb=abc"); got != "https://query2.finance.yahoo.com/v7/finance/options/AAPL" {
+		t.Errorf("matchKey without parameters = %s, want no query", got)
+	}
+	if got := key("https://query2.finance.yahoo.com/v8/finance/chart/AAPL?interval=1wk&events=div%2Csplits"); got == want {
+		t.Errorf("matchKey ignored the interval: %s", got)
+	}
+}
+
+func TestRecorderRecordAndReplay(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "A3", Value: "session"})
+		w.Write([]byte(`{"crumb":"` + r.URL.Query().Get("crumb") + `"}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := NewRecorder(path, CassetteRecord, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	client := &http.Client{Transport: recorder}
+	resp, err := client.Get(srv.URL + "/v7/finance/quote?symbols=AAPL&crumb=first")
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	resp.Body.Close()
+
+	replayer, err := NewRecorder(path, CassetteReplay, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	interaction := replayer.cassette.Interactions[0]
+	if cookie := interaction.Header.Get("Set-Cookie"); cookie != "" {
+		t.Errorf("recorded Set-Cookie %q, want it stripped", cookie)
+	}
+
+	// a different crumb still matches the recording
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v7/finance/quote?crumb=second&symbols=AAPL", nil)
+	resp, err = replayer.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/v7/finance/quote?symbols=MSFT", nil)
+	if _, err := replayer.RoundTrip(req); err == nil {
+		t.Error("replaying an unrecorded request: want an error")
+	}
+}
+
+func TestReplayHistory(t *testing.T) {
+	replayCassette(t, "chart.json")
+
+	history, err := NewTicker("AAPL").History(context.Background(), HistoryQuery{Start: "2024-10-07", Interval: "1d"})
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+
+	// the null bar of 2024-10-09 is left out
+	if len(history) != 4 {
+		t.Fatalf("history has %d bars, want 4: %v", len(history), history)
+	}
+	if _, ok := history["2024-10-09"]; ok {
+		t.Error("history kept the null bar of 2024-10-09")
+	}
+	want := PriceData{Open: 227.78, High: 229.5, Low: 227.17, Close: 229.04, AdjClose: 228.79, Volume: 28183500}
+	if got := history["2024-10-10"]; got != want {
+		t.Errorf("2024-10-10 = %+v, want %+v", got, want)
+	}
+
+	chart, err := NewTicker("AAPL").Chart(context.Background(), HistoryQuery{Start: "2024-10-07", Interval: "1d"})
+	if err != nil {
+		t.Fatalf("Chart: %v", err)
+	}
+	dividend := CorporateAction{Date: "2024-10-09", Type: ActionDividend, Amount: 0.25}
+	if len(chart.Actions) != 1 || chart.Actions[0] != dividend {
+		t.Errorf("actions = %+v, want %+v", chart.Actions, dividend)
+	}
+}
+
+func TestReplayFundamentals(t *testing.T) {
+	replayCassette(t, "quotesummary.json")
+
+	fundamentals, err := NewTicker("AAPL").Fundamentals(context.Background())
+	if err != nil {
+		t.Fatalf("Fundamentals: %v", err)
+	}
+	if fundamentals.Price.Symbol != "AAPL" || fundamentals.Price.Currency != "USD" {
+		t.Errorf("price = %s in %s, want AAPL in USD", fundamentals.Price.Symbol, fundamentals.Price.Currency)
+	}
+	if got := fundamentals.Price.RegularMarketPrice; got == nil || got.Raw != 227.55 {
+		t.Errorf("RegularMarketPrice = %+v, want 227.55", got)
+	}
+
+	// the trailing P/E is reported as an empty object, the forward one is used instead
+	if pe := fundamentals.PE(); pe == nil || pe.Fmt != "30.53" {
+		t.Errorf("PE = %+v, want the forward 30.53", pe)
+	}
+	if beta := fundamentals.Beta(); beta == nil || beta.Raw != 1.24 {
+		t.Errorf("Beta = %+v, want 1.24", beta)
+	}
+	if date, ok := fundamentals.NextEarningsDate(); !ok || date.Format("2006-01-02") != "2024-10-31" {
+		t.Errorf("NextEarningsDate = %s, %v, want 2024-10-31", date, ok)
+	}
+	if got := fundamentals.DefaultKeyStatistics.LastSplitFactor; got == nil || *got != "4:1" {
+		t.Errorf("LastSplitFactor = %v, want 4:1", got)
+	}
+	if got := fundamentals.AssetProfile.Sector; got != "Technology" {
+		t.Errorf("Sector = %s, want Technology", got)
+	}
+	if got := fundamentals.FinancialData.RecommendationKey; got != "buy" {
+		t.Errorf("RecommendationKey = %s, want buy", got)
+	}
+
+	_, err = NewTicker("NOPE").Fundamentals(context.Background())
+	if !errors.Is(err, ErrUnknownSymbol) {
+		t.Errorf("Fundamentals(NOPE) err = %v, want ErrUnknownSymbol", err)
+	}
+}
+
+func TestReplayOptions(t *testing.T) {
+	replayCassette(t, "options.json")
+
+	// the recording starts with a 429, which is retried
+	chain, err := NewTicker("AAPL").OptionChain(context.Background())
+	if err != nil {
+		t.Fatalf("OptionChain: %v", err)
+	}
+	if chain.ExpirationDate != "2024-10-18" || len(chain.Calls) != 2 || len(chain.Puts) != 1 {
+		t.Errorf("chain = %s with %d calls and %d puts, want 2024-10-18 with 2 and 1", chain.ExpirationDate, len(chain.Calls), len(chain.Puts))
+	}
+
+	_, err = NewTicker("AAPL").OptionChainByExpiration(context.Background(), "2024-10-25")
+	if !errors.Is(err, ErrNoOptions) {
+		t.Errorf("OptionChainByExpiration err = %v, want ErrNoOptions", err)
+	}
+}
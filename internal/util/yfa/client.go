@@ -1,48 +1,195 @@
 package yfa
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"math/rand"
 	"net/http"
 	"net/url"
+	"strconv"
 	"sync"
+	"time"
 )
 
+// RetryConfig controls how the Client retries failed requests
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the initial attempt
+	MaxRetries int
+	// BaseDelay is the backoff delay before the first retry, doubled on every following retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay and any Retry-After value sent by Yahoo
+	MaxDelay time.Duration
+}
+
+// DefaultRequestTimeout bounds a single HTTP request, including reading the response body
+var DefaultRequestTimeout = 30 * time.Second
+
+// DefaultRetryConfig is used by clients that are not configured otherwise
+var DefaultRetryConfig = RetryConfig{
+	MaxRetries: 3,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   30 * time.Second,
+}
+
 type Client struct {
-	client  *http.Client
-	cookies []*http.Cookie
-	crumb   string
+	client    *http.Client
+	retry     RetryConfig
+	limiter   *rateLimiter
+	mu        sync.Mutex
+	cookies   []*http.Cookie
+	crumb     string
+	crumbCall *crumbCall
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithRetryConfig sets the retry and backoff behaviour of the Client
+func WithRetryConfig(retry RetryConfig) ClientOption {
+	return func(c *Client) {
+		c.retry = retry
+	}
+}
+
+// WithHTTPClient sets the underlying http.Client
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		c.client = client
+	}
+}
+
+// WithTransport sets the http.RoundTripper requests are sent through, like a Recorder or a fake server's transport
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(c *Client) {
+		c.client = &http.Client{Timeout: c.client.Timeout, Transport: transport}
+	}
+}
+
+// WithRateLimit sets the overall request rate and the per-endpoint budgets of the Client.
+// Endpoints are named after the Yahoo path segment, e.g. "chart", "quoteSummary" or "options", news searches are "news".
+func WithRateLimit(global RateLimit, budgets map[string]RateLimit) ClientOption {
+	return func(c *Client) {
+		c.limiter = newRateLimiter(global, budgets)
+	}
 }
 
 var instance *Client
 var once sync.Once
 
+// NewClient creates a Yahoo Finance client with the given options
+func NewClient(opts ...ClientOption) *Client {
+	c := &Client{
+		client:  &http.Client{Timeout: DefaultRequestTimeout},
+		retry:   DefaultRetryConfig,
+		limiter: newRateLimiter(DefaultRateLimit, DefaultEndpointBudgets),
+		cookies: []*http.Cookie{},
+		crumb:   "",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
 func getClient() *Client {
 	once.Do(func() {
-		instance = &Client{client: &http.Client{}, cookies: []*http.Cookie{}, crumb: ""}
+		instance = NewClient()
 	})
 	return instance
 }
 
-func (c *Client) Get(url string, params url.Values) (*http.Response, error) {
-	c.getCrumb()
-	return c.get(url, params)
+// ConfigureSharedClient replaces the client every Ticker and provider uses.
+// It must be called during startup, before the first request is made.
+func ConfigureSharedClient(opts ...ClientOption) {
+	once.Do(func() {})
+	instance = NewClient(opts...)
 }
 
-func (c *Client) get(url string, params url.Values) (*http.Response, error) {
-	if c.crumb != "" {
-		params.Add("crumb", c.crumb)
+// RateLimitStats returns the rate limiter metrics per endpoint
+func (c *Client) RateLimitStats() map[string]RateLimitStats {
+	return c.limiter.Stats()
+}
+
+// SharedRateLimitStats returns the rate limiter metrics of the shared client
+func SharedRateLimitStats() map[string]RateLimitStats {
+	return getClient().RateLimitStats()
+}
+
+// Get performs a GET request with the crumb and cookies attached.
+// Every attempt waits for the rate limiter, which gives up when ctx is done.
+// Auth errors (401/403) invalidate the crumb and cookies so they are re-acquired on the next attempt,
+// throttling (429) and server errors (5xx) are retried with exponential backoff honoring Retry-After.
+func (c *Client) Get(ctx context.Context, url string, params url.Values) (*http.Response, error) {
+	endpoint := endpointName(url, params)
+
+	var lastErr error
+	for attempt := 0; attempt <= c.retry.MaxRetries; attempt++ {
+		if err := c.limiter.Wait(ctx, endpoint); err != nil {
+			return nil, err
+		}
+		crumb, cookies, err := c.getCrumb(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		query := maps.Clone(params)
+		if query == nil {
+			query = make(map[string][]string)
+		}
+		if crumb != "" {
+			query.Set("crumb", crumb)
+		}
+
+		resp, err := c.get(ctx, url, query, cookies)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			lastErr = err
+			if err := c.wait(ctx, attempt, nil); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+			resp.Body.Close()
+			lastErr = fmt.Errorf("unauthorized request to %s: status %d", url, resp.StatusCode)
+			slog.Warn("Yahoo Finance rejected the crumb, refreshing", slog.Int("status", resp.StatusCode), slog.Int("attempt", attempt))
+			c.invalidateCrumb()
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusTooManyRequests {
+				c.limiter.Throttled(endpoint)
+				lastErr = &ThrottledError{Endpoint: endpoint}
+			} else {
+				lastErr = fmt.Errorf("request to %s failed: status %d", url, resp.StatusCode)
+			}
+			slog.Warn("Yahoo Finance request failed, backing off", slog.Int("status", resp.StatusCode), slog.Int("attempt", attempt))
+			if err := c.wait(ctx, attempt, resp); err != nil {
+				return nil, err
+			}
+		default:
+			return resp, nil
+		}
 	}
+
+	return nil, fmt.Errorf("giving up after %d attempts: %w", c.retry.MaxRetries+1, lastErr)
+}
+
+func (c *Client) get(ctx context.Context, url string, params url.Values, cookies []*http.Cookie) (*http.Response, error) {
 	url = fmt.Sprintf("%s?%s", url, params.Encode())
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		slog.Error("Failed to create request", "err", err)
 		return nil, err
 	}
 
-	for _, cookie := range c.cookies {
+	for _, cookie := range cookies {
 		req.AddCookie(cookie)
 	}
 	req.Header.Set("User-Agent", USER_AGENTS[rand.Intn(len(USER_AGENTS))])
@@ -55,29 +202,132 @@ func (c *Client) get(url string, params url.Values) (*http.Response, error) {
 	return resp, nil
 }
 
-func (c *Client) getCookie() {
-	if len(c.cookies) > 0 {
-		return
+// wait sleeps before the next attempt, using the Retry-After header if Yahoo sent one
+func (c *Client) wait(ctx context.Context, attempt int, resp *http.Response) error {
+	if attempt >= c.retry.MaxRetries {
+		return nil
+	}
+	delay := c.backoff(attempt)
+	if after, ok := retryAfter(resp); ok {
+		delay = min(after, c.retry.MaxDelay)
 	}
 
-	endpoint := "https://fc.yahoo.com"
-	resp, err := c.get(endpoint, url.Values{})
-	if err != nil {
-		slog.Error("Failed to get cookie", "err", err)
-		return
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
+}
+
+// backoff returns the exponential backoff delay for the attempt with equal jitter applied
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retry.BaseDelay << attempt
+	if delay <= 0 || delay > c.retry.MaxDelay {
+		delay = c.retry.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// retryAfter parses the Retry-After header, which is either a number of seconds or an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+func (c *Client) invalidateCrumb() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.crumb = ""
+	c.cookies = []*http.Cookie{}
+}
 
-	c.cookies = resp.Cookies()
+// crumbCall is an in-flight crumb fetch, done is closed once the crumb and cookies are stored
+type crumbCall struct {
+	done chan struct{}
 }
 
-func (c *Client) getCrumb() {
+// getCrumb returns the cached crumb and cookies, acquiring new ones if they were invalidated.
+// Concurrent callers share a single fetch, which runs outside the lock so every caller can give up when its ctx is done.
+func (c *Client) getCrumb(ctx context.Context) (string, []*http.Cookie, error) {
+	c.mu.Lock()
 	if c.crumb != "" {
-		return
+		defer c.mu.Unlock()
+		return c.crumb, c.cookies, nil
+	}
+	call := c.crumbCall
+	if call == nil {
+		call = &crumbCall{done: make(chan struct{})}
+		c.crumbCall = call
+		// the fetch outlives a cancelled first caller, the others are still waiting for it
+		go c.fetchCrumb(context.WithoutCancel(ctx), call, c.cookies)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return "", nil, ctx.Err()
+	case <-call.done:
 	}
 
-	c.getCookie()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.crumb, c.cookies, nil
+}
+
+// fetchCrumb acquires the cookies when missing and a crumb for them, then stores both and releases the waiters
+func (c *Client) fetchCrumb(ctx context.Context, call *crumbCall, cookies []*http.Cookie) {
+	ctx, cancel := context.WithTimeout(ctx, DefaultRequestTimeout)
+	defer cancel()
+
+	crumb := ""
+	defer func() {
+		c.mu.Lock()
+		c.crumb, c.cookies = crumb, cookies
+		c.crumbCall = nil
+		c.mu.Unlock()
+		close(call.done)
+	}()
+
+	if len(cookies) == 0 {
+		if err := c.limiter.Wait(ctx, "crumb"); err != nil {
+			slog.Error("Failed to get cookie", "err", err)
+			return
+		}
+		resp, err := c.get(ctx, COOKIE_URL, url.Values{}, nil)
+		if err != nil {
+			slog.Error("Failed to get cookie", "err", err)
+			return
+		}
+		resp.Body.Close()
+		cookies = resp.Cookies()
+	}
+
+	if err := c.limiter.Wait(ctx, "crumb"); err != nil {
+		slog.Error("Failed to get crumb", "err", err)
+		return
+	}
 	endpoint := fmt.Sprintf("%s/v1/test/getcrumb", BASE_URL)
-	resp, err := c.get(endpoint, url.Values{})
+	resp, err := c.get(ctx, endpoint, url.Values{}, cookies)
 	if err != nil {
 		slog.Error("Failed to get crumb", "err", err)
 		return
@@ -90,5 +340,10 @@ func (c *Client) getCrumb() {
 		return
 	}
 
-	c.crumb = string(body)
+	if resp.StatusCode != http.StatusOK {
+		slog.Error("Failed to get crumb", slog.Int("status", resp.StatusCode))
+		return
+	}
+
+	crumb = string(body)
 }
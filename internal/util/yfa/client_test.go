@@ -0,0 +1,93 @@
+package yfa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCrumbFetchSharedAndCancellable(t *testing.T) {
+	release := make(chan struct{})
+	var crumbs atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/test/getcrumb", func(w http.ResponseWriter, r *http.Request) {
+		crumbs.Add(1)
+		<-release
+		w.Write([]byte("crumb"))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" && r.URL.Query().Get("crumb") != "crumb" {
+			t.Errorf("request %s without the crumb", r.URL)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	useClient(t)
+	SetBaseURL(srv.URL)
+	client := getClient()
+
+	// a hanging crumb endpoint doesn't hold up a caller past its own deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.Get(ctx, srv.URL+"/v8/finance/chart/AAPL", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get err = %v, want the deadline", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("Get returned after %s, want it to give up at its deadline", waited)
+	}
+
+	// the callers waiting meanwhile share the fetch that is still running
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(context.Background(), srv.URL+"/v8/finance/chart/AAPL", nil)
+			if err != nil {
+				t.Errorf("Get: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := crumbs.Load(); got != 1 {
+		t.Errorf("crumb requests = %d, want 1", got)
+	}
+	if stats := client.RateLimitStats()["crumb"]; stats.Requests != 2 {
+		t.Errorf("crumb limiter requests = %d, want the cookie and the crumb", stats.Requests)
+	}
+}
+
+func TestEndpointName(t *testing.T) {
+	tests := []struct {
+		url    string
+		params url.Values
+		want   string
+	}{
+		{url: "https://query2.finance.yahoo.com/v8/finance/chart/AAPL", want: "chart"},
+		{url: "https://query2.finance.yahoo.com/v7/finance/quote", params: url.Values{"symbols": {"AAPL,MSFT"}}, want: "quote"},
+		{url: "https://query2.finance.yahoo.com/v10/finance/quoteSummary/AAPL", want: "quoteSummary"},
+		{url: "https://query2.finance.yahoo.com/v1/finance/search", params: url.Values{"q": {"apple"}, "quotesCount": {"8"}, "newsCount": {"0"}}, want: "search"},
+		{url: "https://query2.finance.yahoo.com/v1/finance/search", params: url.Values{"q": {"AAPL"}, "quotesCount": {"0"}, "newsCount": {"10"}}, want: "news"},
+		{url: "https://query2.finance.yahoo.com/v1/test/getcrumb", want: "other"},
+	}
+	for _, tt := range tests {
+		if got := endpointName(tt.url, tt.params); got != tt.want {
+			t.Errorf("endpointName(%s, %v) = %s, want %s", tt.url, tt.params, got, tt.want)
+		}
+		if _, ok := DefaultEndpointBudgets[tt.want]; !ok && tt.want != "other" {
+			t.Errorf("no default budget for %s", tt.want)
+		}
+	}
+}
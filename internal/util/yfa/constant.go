@@ -1,6 +1,18 @@
 package yfa
 
+import "strings"
+
 var BASE_URL = "https://query2.finance.yahoo.com"
+
+// COOKIE_URL hands out the session cookie the crumb is tied to
+var COOKIE_URL = "https://fc.yahoo.com"
+
+// SetBaseURL points every request, including the cookie and crumb, at another server like a local fake
+func SetBaseURL(url string) {
+	BASE_URL = strings.TrimSuffix(url, "/")
+	COOKIE_URL = BASE_URL
+}
+
 var USER_AGENTS = []string{
 	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/133.0.0.0 Safari/537.36",
 	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/133.0.0.0 Safari/537.36",
@@ -0,0 +1,49 @@
+package yfa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrUnknownSymbol is matched by errors.Is when Yahoo has no ticker for a symbol
+	ErrUnknownSymbol = errors.New("unknown symbol")
+	// ErrNoOptions is returned when a symbol has no options listed, or none for the requested expiration
+	ErrNoOptions = errors.New("no options listed")
+	// ErrThrottled is matched by errors.Is when Yahoo kept answering 429 until the retries ran out
+	ErrThrottled = errors.New("throttled by yahoo finance")
+	// ErrUnsupported is returned by providers that don't serve a kind of data, like options from Stooq
+	ErrUnsupported = errors.New("not supported by this provider")
+)
+
+// UnknownSymbolError is returned when Yahoo has no ticker for a symbol.
+// Suggestions holds the closest search matches, if any.
+type UnknownSymbolError struct {
+	Symbol      string
+	Suggestions []string
+}
+
+func (e *UnknownSymbolError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("unknown symbol: %s", e.Symbol)
+	}
+	return fmt.Sprintf("unknown symbol: %s, did you mean %s", e.Symbol, strings.Join(e.Suggestions, ", "))
+}
+
+func (e *UnknownSymbolError) Is(target error) bool {
+	return target == ErrUnknownSymbol
+}
+
+// ThrottledError is returned when Yahoo throttled the requests to an endpoint
+type ThrottledError struct {
+	Endpoint string
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("throttled by yahoo finance on the %s endpoint", e.Endpoint)
+}
+
+func (e *ThrottledError) Is(target error) bool {
+	return target == ErrThrottled
+}
@@ -0,0 +1,82 @@
+package yfa
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	ActionDividend = "dividend"
+	ActionSplit    = "split"
+)
+
+// YahooEvents holds the corporate actions of a chart response, keyed by timestamp
+type YahooEvents struct {
+	Dividends map[string]YahooDividend `json:"dividends"`
+	Splits    map[string]YahooSplit    `json:"splits"`
+}
+
+type YahooDividend struct {
+	Amount float64 `json:"amount"`
+	Date   int64   `json:"date"`
+}
+
+type YahooSplit struct {
+	Date        int64   `json:"date"`
+	Numerator   float64 `json:"numerator"`
+	Denominator float64 `json:"denominator"`
+	SplitRatio  string  `json:"splitRatio"`
+}
+
+// CorporateAction is a dividend or split, dated in the exchange's timezone
+type CorporateAction struct {
+	Date string
+	Type string
+	// Amount is the dividend per share
+	Amount float64
+	// Numerator and Denominator describe the split, a 4:1 split has Numerator 4 and Denominator 1
+	Numerator   float64
+	Denominator float64
+}
+
+// Ratio returns how many shares one share becomes after the split
+func (c CorporateAction) Ratio() float64 {
+	if c.Type != ActionSplit || c.Numerator == 0 || c.Denominator == 0 {
+		return 1
+	}
+	return c.Numerator / c.Denominator
+}
+
+// transformActions converts the chart events into corporate actions sorted by date
+func (h *History) transformActions(data YahooHistoryRespose) []CorporateAction {
+	var actions []CorporateAction
+	if len(data.Chart.Result) == 0 {
+		return actions
+	}
+	loc := exchangeLocation(data.Chart.Result[0].Meta)
+	events := data.Chart.Result[0].Events
+
+	for _, dividend := range events.Dividends {
+		actions = append(actions, CorporateAction{
+			Date:   time.Unix(dividend.Date, 0).In(loc).Format("2006-01-02"),
+			Type:   ActionDividend,
+			Amount: dividend.Amount,
+		})
+	}
+	for _, split := range events.Splits {
+		actions = append(actions, CorporateAction{
+			Date:        time.Unix(split.Date, 0).In(loc).Format("2006-01-02"),
+			Type:        ActionSplit,
+			Numerator:   split.Numerator,
+			Denominator: split.Denominator,
+		})
+	}
+
+	sort.Slice(actions, func(i, j int) bool {
+		if actions[i].Date == actions[j].Date {
+			return actions[i].Type < actions[j].Type
+		}
+		return actions[i].Date < actions[j].Date
+	})
+	return actions
+}
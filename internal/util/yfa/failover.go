@@ -0,0 +1,155 @@
+package yfa
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// FailoverCooldown is how long a provider that failed is tried after the healthy ones
+var FailoverCooldown = 2 * time.Minute
+
+// NamedProvider is a MarketDataProvider with the name it is logged and configured as
+type NamedProvider struct {
+	Name     string
+	Provider MarketDataProvider
+}
+
+// FailoverProvider tries its providers in priority order and falls over to the next one when a request fails.
+// A provider that failed is moved behind the healthy ones for FailoverCooldown, so a Yahoo outage
+// doesn't cost a round of retries on every request.
+type FailoverProvider struct {
+	providers []NamedProvider
+
+	mu     sync.Mutex
+	failed map[string]time.Time
+}
+
+// NewFailoverProvider creates a MarketDataProvider over providers, highest priority first
+func NewFailoverProvider(providers ...NamedProvider) *FailoverProvider {
+	return &FailoverProvider{
+		providers: providers,
+		failed:    make(map[string]time.Time),
+	}
+}
+
+// order returns the providers with the ones in their cooldown moved to the back
+func (f *FailoverProvider) order() []NamedProvider {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	healthy := make([]NamedProvider, 0, len(f.providers))
+	var cooling []NamedProvider
+	for _, provider := range f.providers {
+		if time.Since(f.failed[provider.Name]) < FailoverCooldown {
+			cooling = append(cooling, provider)
+		} else {
+			healthy = append(healthy, provider)
+		}
+	}
+	return append(healthy, cooling...)
+}
+
+func (f *FailoverProvider) markFailed(name string, failed bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if failed {
+		f.failed[name] = time.Now()
+	} else {
+		delete(f.failed, name)
+	}
+}
+
+// isAnswer reports whether err is the provider's answer about the symbol, like an unknown symbol,
+// which stops the failover as the next provider won't know better
+func isAnswer(err error) bool {
+	return errors.Is(err, ErrUnknownSymbol) || errors.Is(err, ErrNoOptions)
+}
+
+// isOutage reports whether err means the provider couldn't answer, as opposed to an answer or an unsupported request
+func isOutage(err error) bool {
+	return !isAnswer(err) && !errors.Is(err, ErrUnsupported)
+}
+
+// failover calls fn on each provider until one succeeds or answers with an error like an unknown symbol.
+// Otherwise the error returned is the one of the highest priority provider that supports the request.
+func failover[T any](ctx context.Context, f *FailoverProvider, fn func(MarketDataProvider) (T, error)) (T, error) {
+	var zero T
+	var firstErr error
+	for _, provider := range f.order() {
+		value, err := fn(provider.Provider)
+		if err == nil {
+			f.markFailed(provider.Name, false)
+			return value, nil
+		}
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+
+		if isAnswer(err) {
+			f.markFailed(provider.Name, false)
+			return zero, err
+		}
+
+		if isOutage(err) {
+			slog.Warn("Market data provider failed, trying the next one", slog.String("provider", provider.Name), slog.Any("err", err))
+			f.markFailed(provider.Name, true)
+		}
+		if firstErr == nil || errors.Is(firstErr, ErrUnsupported) {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = errors.New("no market data providers configured")
+	}
+	return zero, firstErr
+}
+
+func (f *FailoverProvider) Quote(ctx context.Context, symbol string) (PriceData, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (PriceData, error) { return p.Quote(ctx, symbol) })
+}
+
+func (f *FailoverProvider) Info(ctx context.Context, symbol string) (YahooTickerInfo, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (YahooTickerInfo, error) { return p.Info(ctx, symbol) })
+}
+
+func (f *FailoverProvider) Fundamentals(ctx context.Context, symbol string) (Fundamentals, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (Fundamentals, error) { return p.Fundamentals(ctx, symbol) })
+}
+
+func (f *FailoverProvider) Quotes(ctx context.Context, symbols []string) (map[string]YahooTickerInfo, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (map[string]YahooTickerInfo, error) { return p.Quotes(ctx, symbols) })
+}
+
+func (f *FailoverProvider) History(ctx context.Context, symbol string, query HistoryQuery) (map[string]PriceData, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (map[string]PriceData, error) { return p.History(ctx, symbol, query) })
+}
+
+func (f *FailoverProvider) Chart(ctx context.Context, symbol string, query HistoryQuery) (ChartData, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (ChartData, error) { return p.Chart(ctx, symbol, query) })
+}
+
+func (f *FailoverProvider) OptionChain(ctx context.Context, symbol string) (OptionData, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (OptionData, error) { return p.OptionChain(ctx, symbol) })
+}
+
+func (f *FailoverProvider) OptionChainByExpiration(ctx context.Context, symbol, expiration string) (OptionData, error) {
+	return failover(ctx, f, func(p MarketDataProvider) (OptionData, error) {
+		return p.OptionChainByExpiration(ctx, symbol, expiration)
+	})
+}
+
+func (f *FailoverProvider) ExpirationDates(ctx context.Context, symbol string) ([]string, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]string, error) { return p.ExpirationDates(ctx, symbol) })
+}
+
+func (f *FailoverProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]SearchResult, error) { return p.Search(ctx, query) })
+}
+
+func (f *FailoverProvider) News(ctx context.Context, symbol string) ([]NewsItem, error) {
+	return failover(ctx, f, func(p MarketDataProvider) ([]NewsItem, error) { return p.News(ctx, symbol) })
+}
@@ -0,0 +1,119 @@
+package yfa
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stubProvider answers Quote with price, or err when set, and counts the calls
+type stubProvider struct {
+	MarketDataProvider
+	price PriceData
+	err   error
+	calls int
+}
+
+func (s *stubProvider) Quote(ctx context.Context, symbol string) (PriceData, error) {
+	s.calls++
+	return s.price, s.err
+}
+
+func TestFailoverOrder(t *testing.T) {
+	outage := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		primary   error
+		secondary error
+		wantErr   error
+		wantCalls [2]int
+		wantPrice float64
+	}{
+		{name: "primary answers", wantCalls: [2]int{1, 0}, wantPrice: 1},
+		{name: "primary down", primary: outage, wantCalls: [2]int{1, 1}, wantPrice: 2},
+		{name: "both down", primary: outage, secondary: errors.New("timeout"), wantErr: outage, wantCalls: [2]int{1, 1}},
+		{name: "unknown symbol", primary: &UnknownSymbolError{Symbol: "NOPE"}, wantErr: ErrUnknownSymbol, wantCalls: [2]int{1, 0}},
+		{name: "no options", primary: ErrNoOptions, wantErr: ErrNoOptions, wantCalls: [2]int{1, 0}},
+		{name: "unsupported", primary: ErrUnsupported, wantCalls: [2]int{1, 1}, wantPrice: 2},
+		{name: "unsupported then down", primary: ErrUnsupported, secondary: outage, wantErr: outage, wantCalls: [2]int{1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &stubProvider{price: PriceData{Close: 1}, err: tt.primary}
+			secondary := &stubProvider{price: PriceData{Close: 2}, err: tt.secondary}
+			f := NewFailoverProvider(NamedProvider{"yahoo", primary}, NamedProvider{"stooq", secondary})
+
+			price, err := f.Quote(context.Background(), "AAPL")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("err = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil || price.Close != tt.wantPrice {
+				t.Errorf("Quote = %+v, %v, want the close of %v", price, err, tt.wantPrice)
+			}
+			if got := [2]int{primary.calls, secondary.calls}; got != tt.wantCalls {
+				t.Errorf("calls = %v, want %v", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestFailoverCooldown(t *testing.T) {
+	cooldown := FailoverCooldown
+	t.Cleanup(func() { FailoverCooldown = cooldown })
+	FailoverCooldown = time.Hour
+
+	primary := &stubProvider{price: PriceData{Close: 1}, err: errors.New("connection refused")}
+	secondary := &stubProvider{price: PriceData{Close: 2}}
+	f := NewFailoverProvider(NamedProvider{"yahoo", primary}, NamedProvider{"stooq", secondary})
+	ctx := context.Background()
+
+	if _, err := f.Quote(ctx, "AAPL"); err != nil {
+		t.Fatalf("Quote: %v", err)
+	}
+
+	// the failed primary is skipped while it cools down, even once it recovered
+	primary.err = nil
+	price, err := f.Quote(ctx, "AAPL")
+	if err != nil || price.Close != 2 {
+		t.Errorf("Quote during the cooldown = %+v, %v, want the secondary", price, err)
+	}
+	if primary.calls != 1 || secondary.calls != 2 {
+		t.Errorf("calls = %d and %d, want 1 and 2", primary.calls, secondary.calls)
+	}
+
+	// when the secondary fails too, the primary is still tried last
+	secondary.err = errors.New("timeout")
+	price, err = f.Quote(ctx, "AAPL")
+	if err != nil || price.Close != 1 {
+		t.Errorf("Quote with the secondary down = %+v, %v, want the primary", price, err)
+	}
+
+	// the primary answered, so it's back in front now the secondary is cooling down
+	FailoverCooldown = 0
+	secondary.err = nil
+	if price, err := f.Quote(ctx, "AAPL"); err != nil || price.Close != 1 {
+		t.Errorf("Quote after the cooldown = %+v, %v, want the primary", price, err)
+	}
+	if primary.calls != 3 || secondary.calls != 3 {
+		t.Errorf("calls = %d and %d, want 3 and 3", primary.calls, secondary.calls)
+	}
+}
+
+func TestFailoverCancelled(t *testing.T) {
+	primary := &stubProvider{err: context.Canceled}
+	secondary := &stubProvider{}
+	f := NewFailoverProvider(NamedProvider{"yahoo", primary}, NamedProvider{"stooq", secondary})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := f.Quote(ctx, "AAPL"); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if secondary.calls != 0 {
+		t.Errorf("secondary calls = %d, want none after the cancel", secondary.calls)
+	}
+}
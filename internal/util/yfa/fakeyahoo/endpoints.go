@@ -0,0 +1,375 @@
+package fakeyahoo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stollenaar/stockbot/internal/util/pricing"
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+)
+
+// riskFreeRate prices the generated option contracts
+const riskFreeRate = 0.04
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func notFound(description string) map[string]any {
+	return map[string]any{"code": "Not Found", "description": description}
+}
+
+// priceValue formats v like the raw/fmt pairs of the quoteSummary endpoint
+func priceValue(v float64) map[string]any {
+	return map[string]any{"raw": v, "fmt": strconv.FormatFloat(v, 'f', 2, 64)}
+}
+
+func (f *Fake) handleChart(w http.ResponseWriter, r *http.Request) {
+	s, ok := f.symbol(r.PathValue("symbol"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"chart": map[string]any{"result": nil, "error": notFound("No data found, symbol may be delisted")},
+		})
+		return
+	}
+
+	query := r.URL.Query()
+	interval := query.Get("interval")
+	if interval == "" {
+		interval = "1d"
+	}
+	end := time.Now()
+	if period2, err := strconv.ParseInt(query.Get("period2"), 10, 64); err == nil {
+		end = time.Unix(period2, 0)
+	}
+	start := rangeStart(query.Get("range"), end)
+	if period1, err := strconv.ParseInt(query.Get("period1"), 10, 64); err == nil {
+		start = time.Unix(period1, 0)
+	}
+
+	var bars []Bar
+	if strings.HasSuffix(interval, "m") && !strings.HasSuffix(interval, "mo") {
+		bars = intradayBars(s)
+	} else {
+		for _, bar := range s.History {
+			if !bar.Time.Before(start) && !bar.Time.After(end) {
+				bars = append(bars, bar)
+			}
+		}
+	}
+
+	loc, _ := time.LoadLocation(s.Timezone)
+	_, offset := time.Now().In(loc).Zone()
+	result := yfa.YahooHistoryResult{
+		Meta: yfa.YahooMeta{
+			Currency:             s.Currency,
+			Symbol:               s.Symbol,
+			ExchangeName:         s.Exchange,
+			InstrumentType:       "EQUITY",
+			GmtOffset:            offset,
+			Timezone:             "EST",
+			ExchangeTimezoneName: s.Timezone,
+			RegularMarketPrice:   s.Price(),
+			ChartPreviousClose:   s.PreviousClose(),
+			PreviousClose:        s.PreviousClose(),
+			LongName:             s.Name,
+			ShortName:            s.Name,
+			PriceHint:            2,
+			DataGranularity:      interval,
+		},
+	}
+
+	var quote yfa.YahooQuote
+	var adjClose yfa.YahooAdjClose
+	for _, bar := range bars {
+		result.Timestamp = append(result.Timestamp, bar.Time.Unix())
+		if bar.Close == nil {
+			quote.Open = append(quote.Open, nil)
+			quote.High = append(quote.High, nil)
+			quote.Low = append(quote.Low, nil)
+			quote.Close = append(quote.Close, nil)
+			quote.Volume = append(quote.Volume, nil)
+			adjClose.AdjClose = append(adjClose.AdjClose, nil)
+			continue
+		}
+		closing := *bar.Close
+		quote.Open = append(quote.Open, pointer(closing*0.995))
+		quote.High = append(quote.High, pointer(closing*1.01))
+		quote.Low = append(quote.Low, pointer(closing*0.99))
+		quote.Close = append(quote.Close, pointer(closing))
+		quote.Volume = append(quote.Volume, pointer(bar.Volume))
+		adjClose.AdjClose = append(adjClose.AdjClose, pointer(closing))
+	}
+	result.Indicators.Quote = []yfa.YahooQuote{quote}
+	if interval == "1d" {
+		result.Indicators.AdjClose = []yfa.YahooAdjClose{adjClose}
+	}
+
+	writeJSON(w, http.StatusOK, yfa.YahooHistoryRespose{Chart: yfa.YahooChart{Result: []yfa.YahooHistoryResult{result}}})
+}
+
+// rangeStart returns the start of a chart range like 5d, 1mo or 1y
+func rangeStart(chartRange string, end time.Time) time.Time {
+	for suffix, add := range map[string]func(n int) time.Time{
+		"mo": func(n int) time.Time { return end.AddDate(0, -n, 0) },
+		"d":  func(n int) time.Time { return end.AddDate(0, 0, -n) },
+		"y":  func(n int) time.Time { return end.AddDate(-n, 0, 0) },
+	} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(chartRange, suffix)); err == nil && strings.HasSuffix(chartRange, suffix) {
+			return add(n)
+		}
+	}
+	return end.AddDate(0, -1, 0)
+}
+
+// intradayBars spreads the move from the previous close to the current price over the minutes of the last session
+func intradayBars(s Symbol) []Bar {
+	if len(s.History) == 0 {
+		return nil
+	}
+	open := s.History[len(s.History)-1].Time
+	previous, price := s.PreviousClose(), s.Price()
+
+	minutes := min(390, max(1, int(time.Since(open).Minutes())))
+	bars := make([]Bar, 0, minutes)
+	for minute := range minutes {
+		closing := previous + (price-previous)*float64(minute+1)/float64(minutes)
+		bars = append(bars, Bar{Time: open.Add(time.Duration(minute) * time.Minute), Close: pointer(closing), Volume: 10_000})
+	}
+	return bars
+}
+
+func (f *Fake) handleQuote(w http.ResponseWriter, r *http.Request) {
+	var results []map[string]any
+	for _, symbol := range strings.Split(r.URL.Query().Get("symbols"), ",") {
+		s, ok := f.symbol(symbol)
+		if !ok {
+			continue
+		}
+		price, previous := s.Price(), s.PreviousClose()
+		results = append(results, map[string]any{
+			"symbol":                     s.Symbol,
+			"shortName":                  s.Name,
+			"longName":                   s.Name,
+			"currency":                   s.Currency,
+			"exchange":                   s.Exchange,
+			"fullExchangeName":           s.Exchange,
+			"quoteType":                  "EQUITY",
+			"marketState":                "REGULAR",
+			"priceHint":                  2,
+			"regularMarketTime":          time.Now().Unix(),
+			"regularMarketPrice":         price,
+			"regularMarketChange":        price - previous,
+			"regularMarketChangePercent": (price - previous) / previous * 100,
+			"regularMarketPreviousClose": previous,
+			"regularMarketOpen":          previous,
+			"regularMarketDayHigh":       math.Max(price, previous),
+			"regularMarketDayLow":        math.Min(price, previous),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"quoteResponse": map[string]any{"result": results, "error": nil},
+	})
+}
+
+func (f *Fake) handleQuoteSummary(w http.ResponseWriter, r *http.Request) {
+	s, ok := f.symbol(r.PathValue("symbol"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"quoteSummary": map[string]any{"result": nil, "error": notFound(fmt.Sprintf("Quote not found for symbol: %s", r.PathValue("symbol")))},
+		})
+		return
+	}
+
+	price, previous := s.Price(), s.PreviousClose()
+	low, high := price, price
+	for _, bar := range s.History[max(0, len(s.History)-252):] {
+		if bar.Close != nil {
+			low, high = math.Min(low, *bar.Close), math.Max(high, *bar.Close)
+		}
+	}
+
+	modules := map[string]any{}
+	for _, module := range strings.Split(r.URL.Query().Get("modules"), ",") {
+		switch module {
+		case "price":
+			modules[module] = map[string]any{
+				"symbol":                     s.Symbol,
+				"shortName":                  s.Name,
+				"longName":                   s.Name,
+				"currency":                   s.Currency,
+				"currencySymbol":             "$",
+				"exchange":                   s.Exchange,
+				"exchangeName":               s.Exchange,
+				"quoteType":                  "EQUITY",
+				"marketState":                "REGULAR",
+				"regularMarketTime":          time.Now().Unix(),
+				"regularMarketPrice":         priceValue(price),
+				"regularMarketChange":        priceValue(price - previous),
+				"regularMarketChangePercent": map[string]any{"raw": (price - previous) / previous, "fmt": fmt.Sprintf("%.2f%%", (price-previous)/previous*100)},
+				"regularMarketPreviousClose": priceValue(previous),
+				"regularMarketOpen":          priceValue(previous),
+				"regularMarketDayHigh":       priceValue(math.Max(price, previous)),
+				"regularMarketDayLow":        priceValue(math.Min(price, previous)),
+			}
+		case "summaryDetail":
+			modules[module] = map[string]any{
+				"previousClose":    priceValue(previous),
+				"fiftyTwoWeekLow":  priceValue(low),
+				"fiftyTwoWeekHigh": priceValue(high),
+				"currency":         s.Currency,
+			}
+		default:
+			modules[module] = map[string]any{}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"quoteSummary": map[string]any{"result": []any{modules}, "error": nil},
+	})
+}
+
+// expirations returns the next four Fridays as UTC midnight, the form Yahoo lists expirations in
+func expirations() []time.Time {
+	now := time.Now().UTC()
+	friday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for friday.Weekday() != time.Friday {
+		friday = friday.AddDate(0, 0, 1)
+	}
+
+	dates := make([]time.Time, 0, 4)
+	for week := range 4 {
+		dates = append(dates, friday.AddDate(0, 0, 7*week))
+	}
+	return dates
+}
+
+func (f *Fake) handleOptions(w http.ResponseWriter, r *http.Request) {
+	s, ok := f.symbol(r.PathValue("symbol"))
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]any{
+			"optionChain": map[string]any{"result": []any{}, "error": notFound("No data found")},
+		})
+		return
+	}
+
+	price := s.Price()
+	dates := expirations()
+	result := yfa.YahooOptionResult{
+		UnderlyingSymbol: s.Symbol,
+		Quote:            yfa.YahooOptionQuote{Symbol: s.Symbol, RegularMarketPrice: price, Currency: s.Currency},
+	}
+	for _, date := range dates {
+		result.ExpirationDates = append(result.ExpirationDates, date.Unix())
+	}
+
+	expiration := dates[0]
+	if date, err := strconv.ParseInt(r.URL.Query().Get("date"), 10, 64); err == nil {
+		expiration = time.Unix(date, 0).UTC()
+	}
+
+	listed := false
+	for _, date := range dates {
+		listed = listed || date.Equal(expiration)
+	}
+	if listed {
+		chain := yfa.YahooOptions{ExpirationDate: expiration.Unix()}
+		step := strikeStep(price)
+		for strike := math.Floor(price*0.8/step) * step; strike <= price*1.2; strike += step {
+			result.Strikes = append(result.Strikes, strike)
+			chain.Calls = append(chain.Calls, contract(s, true, strike, price, expiration))
+			chain.Puts = append(chain.Puts, contract(s, false, strike, price, expiration))
+		}
+		result.Options = []yfa.YahooOptions{chain}
+	}
+
+	writeJSON(w, http.StatusOK, yfa.YahooOptionResponse{OptionChain: yfa.YahooOptionChain{Result: []yfa.YahooOptionResult{result}}})
+}
+
+// strikeStep returns a strike spacing that gives roughly twenty strikes on each side
+func strikeStep(price float64) float64 {
+	switch {
+	case price < 25:
+		return 0.5
+	case price < 100:
+		return 1
+	case price < 250:
+		return 2.5
+	default:
+		return 5
+	}
+}
+
+// contract prices a generated contract with an implied volatility smile around the money
+func contract(s Symbol, call bool, strike, price float64, expiration time.Time) yfa.YahooOption {
+	moneyness := math.Log(strike / price)
+	iv := 0.25 + 2*moneyness*moneyness
+	years := max(expiration.Add(20*time.Hour).Sub(time.Now()).Hours()/24/365, 0)
+	value := pricing.BlackScholes(call, price, strike, years, riskFreeRate, iv).Value
+
+	kind := "P"
+	if call {
+		kind = "C"
+	}
+	return yfa.YahooOption{
+		ContractSymbol:    fmt.Sprintf("%s%s%s%08d", s.Symbol, expiration.Format("060102"), kind, int(strike*1000)),
+		Strike:            strike,
+		Currency:          s.Currency,
+		LastPrice:         math.Round(value*100) / 100,
+		Volume:            100,
+		OpenInterest:      1000,
+		Bid:               math.Round(value*0.98*100) / 100,
+		Ask:               math.Round(value*1.02*100) / 100,
+		ContractSize:      "REGULAR",
+		Expiration:        expiration.Unix(),
+		LastTradeDate:     time.Now().Unix(),
+		ImpliedVolatility: iv,
+		InTheMoney:        (call && strike < price) || (!call && strike > price),
+	}
+}
+
+func (f *Fake) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.ToUpper(r.URL.Query().Get("q"))
+	quotesCount, err := strconv.Atoi(r.URL.Query().Get("quotesCount"))
+	if err != nil {
+		quotesCount = 10
+	}
+	newsCount, _ := strconv.Atoi(r.URL.Query().Get("newsCount"))
+
+	f.mu.Lock()
+	response := yfa.YahooSearchResponse{Quotes: []yfa.YahooSearchQuote{}, News: []yfa.YahooNewsItem{}}
+	for _, s := range f.symbols {
+		if len(response.Quotes) >= quotesCount {
+			break
+		}
+		if strings.HasPrefix(s.Symbol, query) || strings.Contains(strings.ToUpper(s.Name), query) {
+			response.Quotes = append(response.Quotes, yfa.YahooSearchQuote{
+				Symbol:         s.Symbol,
+				ShortName:      s.Name,
+				LongName:       s.Name,
+				Exchange:       s.Exchange,
+				ExchangeName:   s.Exchange,
+				QuoteType:      "EQUITY",
+				TypeName:       "Equity",
+				IsYahooFinance: true,
+			})
+		}
+	}
+	// newest first, like Yahoo
+	for i := len(f.news) - 1; i >= 0 && len(response.News) < newsCount; i-- {
+		if slices.Contains(f.news[i].RelatedTickers, query) {
+			response.News = append(response.News, f.news[i])
+		}
+	}
+	f.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, response)
+}
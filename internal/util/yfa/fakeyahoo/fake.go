@@ -0,0 +1,273 @@
+// Package fakeyahoo is a local stand-in for the Yahoo Finance API.
+// It serves the chart, quote, quoteSummary, options, search/news, cookie and crumb endpoints from scriptable in-memory symbols,
+// so the bot can run end to end without network access. Point yfa at it with yfa.SetBaseURL.
+package fakeyahoo
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stollenaar/stockbot/internal/util/yfa"
+)
+
+// Crumb is the crumb handed out by the fake
+const Crumb = "fakecrumb"
+
+// historyDays is how far back the generated daily history goes
+const historyDays = 5 * 365
+
+// Bar is a single daily bar, a nil Close is served as a null bar like Yahoo does for days without trades
+type Bar struct {
+	Time   time.Time
+	Close  *float64
+	Volume int64
+}
+
+// Symbol is a ticker served by the fake
+type Symbol struct {
+	Symbol   string
+	Name     string
+	Currency string
+	Exchange string
+	Timezone string
+	// History holds the daily bars in ascending order, the last bar is the current session
+	History []Bar
+}
+
+// Price returns the close of the current session
+func (s *Symbol) Price() float64 {
+	for i := len(s.History) - 1; i >= 0; i-- {
+		if s.History[i].Close != nil {
+			return *s.History[i].Close
+		}
+	}
+	return 0
+}
+
+// PreviousClose returns the close of the session before the current one
+func (s *Symbol) PreviousClose() float64 {
+	for i := len(s.History) - 2; i >= 0; i-- {
+		if s.History[i].Close != nil {
+			return *s.History[i].Close
+		}
+	}
+	return s.Price()
+}
+
+// Fake serves the Yahoo Finance endpoints, it is safe for concurrent use
+type Fake struct {
+	mu         sync.Mutex
+	symbols    map[string]*Symbol
+	throttle   int
+	retryAfter time.Duration
+	requests   map[string]int
+	news       []yfa.YahooNewsItem
+	mux        *http.ServeMux
+}
+
+// New creates a fake without symbols
+func New() *Fake {
+	f := &Fake{
+		symbols:    make(map[string]*Symbol),
+		retryAfter: time.Second,
+		requests:   make(map[string]int),
+		mux:        http.NewServeMux(),
+	}
+	f.mux.HandleFunc("GET /{$}", f.handleCookie)
+	f.mux.HandleFunc("GET /v1/test/getcrumb", f.handleCrumb)
+	f.mux.HandleFunc("GET /v8/finance/chart/{symbol}", f.throttled("chart", f.handleChart))
+	f.mux.HandleFunc("GET /v7/finance/quote", f.throttled("quote", f.handleQuote))
+	f.mux.HandleFunc("GET /v10/finance/quoteSummary/{symbol}", f.throttled("quoteSummary", f.handleQuoteSummary))
+	f.mux.HandleFunc("GET /v7/finance/options/{symbol}", f.throttled("options", f.handleOptions))
+	f.mux.HandleFunc("GET /v1/finance/search", f.throttled("search", f.handleSearch))
+	return f
+}
+
+// Start serves the fake on a local httptest server, close it when done
+func (f *Fake) Start() *httptest.Server {
+	return httptest.NewServer(f)
+}
+
+func (f *Fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mux.ServeHTTP(w, r)
+}
+
+// AddSymbol adds a ticker with a generated daily history that ends at price.
+// The history is a random walk seeded by the symbol, so every run serves the same prices.
+func (f *Fake) AddSymbol(symbol, name string, price float64) *Symbol {
+	symbol = strings.ToUpper(symbol)
+
+	hash := fnv.New64a()
+	hash.Write([]byte(symbol))
+	random := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+	loc, _ := time.LoadLocation("America/New_York")
+	now := time.Now().In(loc)
+	open := time.Date(now.Year(), now.Month(), now.Day(), 9, 30, 0, 0, loc)
+
+	var bars []Bar
+	closing := price
+	for day := 0; day < historyDays; day++ {
+		date := open.AddDate(0, 0, -day)
+		if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+			continue
+		}
+		bars = append(bars, Bar{Time: date, Close: pointer(closing), Volume: 1_000_000 + random.Int63n(9_000_000)})
+		closing = closing / (1 + (random.Float64()-0.5)*0.04)
+	}
+	for i, j := 0, len(bars)-1; i < j; i, j = i+1, j-1 {
+		bars[i], bars[j] = bars[j], bars[i]
+	}
+
+	s := &Symbol{
+		Symbol:   symbol,
+		Name:     name,
+		Currency: "USD",
+		Exchange: "NMS",
+		Timezone: "America/New_York",
+		History:  bars,
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.symbols[symbol] = s
+	return s
+}
+
+// SetPrice moves the current session close of the symbol to price
+func (f *Fake) SetPrice(symbol string, price float64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.symbols[strings.ToUpper(symbol)]
+	if !ok || len(s.History) == 0 {
+		return fmt.Errorf("unknown symbol: %s", symbol)
+	}
+	s.History[len(s.History)-1].Close = pointer(price)
+	return nil
+}
+
+// Move changes the current session close of the symbol by percent
+func (f *Fake) Move(symbol string, percent float64) error {
+	f.mu.Lock()
+	s, ok := f.symbols[strings.ToUpper(symbol)]
+	var price float64
+	if ok {
+		price = s.Price()
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("unknown symbol: %s", symbol)
+	}
+	return f.SetPrice(symbol, price*(1+percent/100))
+}
+
+// SetNulls turns the count sessions before the current one into null bars
+func (f *Fake) SetNulls(symbol string, count int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.symbols[strings.ToUpper(symbol)]
+	if !ok {
+		return fmt.Errorf("unknown symbol: %s", symbol)
+	}
+	for i := max(0, len(s.History)-1-count); i < len(s.History)-1; i++ {
+		s.History[i].Close = nil
+	}
+	return nil
+}
+
+// AddNews publishes a headline about the symbol, it returns the id of the item
+func (f *Fake) AddNews(symbol, title, publisher string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := fmt.Sprintf("fake-news-%d", len(f.news)+1)
+	f.news = append(f.news, yfa.YahooNewsItem{
+		UUID:                id,
+		Title:               title,
+		Publisher:           publisher,
+		Link:                fmt.Sprintf("https://example.com/news/%s", id),
+		ProviderPublishTime: time.Now().Unix(),
+		Type:                "STORY",
+		RelatedTickers:      []string{strings.ToUpper(symbol)},
+	})
+	return id
+}
+
+// Throttle answers the next count data requests with a 429 and a Retry-After of retryAfter
+func (f *Fake) Throttle(count int, retryAfter time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.throttle = count
+	f.retryAfter = retryAfter
+}
+
+// Requests returns the number of requests served per endpoint, throttled ones included
+func (f *Fake) Requests() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	snapshot := make(map[string]int, len(f.requests))
+	for endpoint, count := range f.requests {
+		snapshot[endpoint] = count
+	}
+	return snapshot
+}
+
+// symbol returns a copy of the symbol so handlers don't hold the lock while encoding
+func (f *Fake) symbol(symbol string) (Symbol, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.symbols[strings.ToUpper(symbol)]
+	if !ok {
+		return Symbol{}, false
+	}
+	copied := *s
+	copied.History = append([]Bar(nil), s.History...)
+	return copied, true
+}
+
+// throttled counts the request and answers 429 while a throttle is scripted
+func (f *Fake) throttled(endpoint string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		f.requests[endpoint]++
+		throttle := f.throttle > 0
+		if throttle {
+			f.throttle--
+		}
+		retryAfter := f.retryAfter
+		f.mu.Unlock()
+
+		if throttle {
+			w.Header().Set("Retry-After", fmt.Sprintf("%d", int(retryAfter.Seconds())))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (f *Fake) handleCookie(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: "A3", Value: "fake", Path: "/"})
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *Fake) handleCrumb(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprint(w, Crumb)
+}
+
+func pointer[T any](v T) *T {
+	return &v
+}
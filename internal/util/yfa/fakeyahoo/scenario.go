@@ -0,0 +1,120 @@
+package fakeyahoo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// Scenario seeds the fake with symbols and scripts what happens to them over time
+type Scenario struct {
+	Symbols []ScenarioSymbol `json:"symbols"`
+	Steps   []Step           `json:"steps"`
+}
+
+// ScenarioSymbol is a symbol the scenario starts with
+type ScenarioSymbol struct {
+	Symbol string  `json:"symbol"`
+	Name   string  `json:"name"`
+	Price  float64 `json:"price"`
+}
+
+// Step is a single scripted change, At is the delay since the scenario started, like "30s" or "2m"
+type Step struct {
+	At     string `json:"at"`
+	Symbol string `json:"symbol,omitempty"`
+	// Price sets the current price, Move changes it by a percentage
+	Price *float64 `json:"price,omitempty"`
+	Move  *float64 `json:"move,omitempty"`
+	// Nulls turns the sessions before the current one into null bars
+	Nulls int `json:"nulls,omitempty"`
+	// News publishes a headline about the symbol from Publisher
+	News      string `json:"news,omitempty"`
+	Publisher string `json:"publisher,omitempty"`
+	// Throttle answers the next requests with a 429, RetryAfter is the Retry-After it sends along
+	Throttle   int    `json:"throttle,omitempty"`
+	RetryAfter string `json:"retryAfter,omitempty"`
+}
+
+// LoadScenario reads a scenario from a JSON file
+func LoadScenario(path string) (Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("failed to read scenario: %w", err)
+	}
+
+	var scenario Scenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		return Scenario{}, fmt.Errorf("failed to decode scenario %s: %w", path, err)
+	}
+	for _, step := range scenario.Steps {
+		if _, err := time.ParseDuration(step.At); err != nil {
+			return Scenario{}, fmt.Errorf("invalid step time %q: %w", step.At, err)
+		}
+	}
+	return scenario, nil
+}
+
+// Seed adds the symbols of the scenario to the fake
+func (f *Fake) Seed(scenario Scenario) {
+	for _, symbol := range scenario.Symbols {
+		f.AddSymbol(symbol.Symbol, symbol.Name, symbol.Price)
+	}
+}
+
+// Play applies the steps of the scenario at their time, it returns when the last step ran or ctx is done
+func (f *Fake) Play(ctx context.Context, scenario Scenario) error {
+	start := time.Now()
+	for _, step := range scenario.Steps {
+		at, err := time.ParseDuration(step.At)
+		if err != nil {
+			return fmt.Errorf("invalid step time %q: %w", step.At, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Until(start.Add(at))):
+		}
+
+		if err := f.apply(step); err != nil {
+			slog.Error("Failed to apply scenario step", slog.String("at", step.At), slog.Any("err", err))
+			continue
+		}
+		slog.Info("Applied scenario step", slog.String("at", step.At), slog.String("symbol", step.Symbol))
+	}
+	return nil
+}
+
+func (f *Fake) apply(step Step) error {
+	if step.Throttle > 0 {
+		retryAfter := time.Second
+		if step.RetryAfter != "" {
+			var err error
+			if retryAfter, err = time.ParseDuration(step.RetryAfter); err != nil {
+				return fmt.Errorf("invalid retryAfter %q: %w", step.RetryAfter, err)
+			}
+		}
+		f.Throttle(step.Throttle, retryAfter)
+	}
+	if step.Price != nil {
+		if err := f.SetPrice(step.Symbol, *step.Price); err != nil {
+			return err
+		}
+	}
+	if step.Move != nil {
+		if err := f.Move(step.Symbol, *step.Move); err != nil {
+			return err
+		}
+	}
+	if step.News != "" {
+		f.AddNews(step.Symbol, step.News, step.Publisher)
+	}
+	if step.Nulls > 0 {
+		return f.SetNulls(step.Symbol, step.Nulls)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package yfa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FundamentalsModules are the quoteSummary modules requested for Fundamentals
+var FundamentalsModules = []string{"price", "summaryDetail", "defaultKeyStatistics", "financialData", "calendarEvents", "assetProfile"}
+
+// YahooFundamentalsResponse --> Struct to hold the result from the Yahoo Finance quoteSummary endpoint with the fundamentals modules
+type YahooFundamentalsResponse struct {
+	QuoteSummary struct {
+		Result []Fundamentals `json:"result"`
+		Error  interface{}    `json:"error"`
+	} `json:"quoteSummary"`
+}
+
+// Fundamentals --> Struct to hold the fundamentals modules of a ticker
+// Yahoo reports missing values as an empty object, so a non-nil PriceValue can still have an empty Fmt.
+type Fundamentals struct {
+	Price                YahooTickerInfo     `json:"price"`
+	SummaryDetail        YahooSummaryDetail  `json:"summaryDetail"`
+	DefaultKeyStatistics YahooKeyStatistics  `json:"defaultKeyStatistics"`
+	FinancialData        YahooFinancialData  `json:"financialData"`
+	CalendarEvents       YahooCalendarEvents `json:"calendarEvents"`
+	AssetProfile         YahooAssetProfile   `json:"assetProfile"`
+}
+
+// YahooSummaryDetail --> Struct to hold the summaryDetail module
+type YahooSummaryDetail struct {
+	PreviousClose               *PriceValue `json:"previousClose"`
+	DividendRate                *PriceValue `json:"dividendRate"`
+	DividendYield               *PriceValue `json:"dividendYield"`
+	ExDividendDate              *PriceValue `json:"exDividendDate"`
+	PayoutRatio                 *PriceValue `json:"payoutRatio"`
+	TrailingAnnualDividendRate  *PriceValue `json:"trailingAnnualDividendRate"`
+	TrailingAnnualDividendYield *PriceValue `json:"trailingAnnualDividendYield"`
+	Beta                        *PriceValue `json:"beta"`
+	TrailingPE                  *PriceValue `json:"trailingPE"`
+	ForwardPE                   *PriceValue `json:"forwardPE"`
+	MarketCap                   *PriceValue `json:"marketCap"`
+	FiftyTwoWeekLow             *PriceValue `json:"fiftyTwoWeekLow"`
+	FiftyTwoWeekHigh            *PriceValue `json:"fiftyTwoWeekHigh"`
+	FiftyDayAverage             *PriceValue `json:"fiftyDayAverage"`
+	TwoHundredDayAverage        *PriceValue `json:"twoHundredDayAverage"`
+	Currency                    string      `json:"currency"`
+}
+
+// YahooKeyStatistics --> Struct to hold the defaultKeyStatistics module
+type YahooKeyStatistics struct {
+	EnterpriseValue   *PriceValue `json:"enterpriseValue"`
+	ForwardPE         *PriceValue `json:"forwardPE"`
+	ProfitMargins     *PriceValue `json:"profitMargins"`
+	SharesOutstanding *PriceValue `json:"sharesOutstanding"`
+	Beta              *PriceValue `json:"beta"`
+	BookValue         *PriceValue `json:"bookValue"`
+	PriceToBook       *PriceValue `json:"priceToBook"`
+	TrailingEps       *PriceValue `json:"trailingEps"`
+	ForwardEps        *PriceValue `json:"forwardEps"`
+	PegRatio          *PriceValue `json:"pegRatio"`
+	LastSplitFactor   *string     `json:"lastSplitFactor"`
+	LastSplitDate     *PriceValue `json:"lastSplitDate"`
+}
+
+// YahooFinancialData --> Struct to hold the financialData module
+type YahooFinancialData struct {
+	CurrentPrice            *PriceValue `json:"currentPrice"`
+	TargetMeanPrice         *PriceValue `json:"targetMeanPrice"`
+	RecommendationKey       string      `json:"recommendationKey"`
+	NumberOfAnalystOpinions *PriceValue `json:"numberOfAnalystOpinions"`
+	TotalRevenue            *PriceValue `json:"totalRevenue"`
+	RevenueGrowth           *PriceValue `json:"revenueGrowth"`
+	GrossMargins            *PriceValue `json:"grossMargins"`
+	OperatingMargins        *PriceValue `json:"operatingMargins"`
+	ProfitMargins           *PriceValue `json:"profitMargins"`
+	ReturnOnEquity          *PriceValue `json:"returnOnEquity"`
+	DebtToEquity            *PriceValue `json:"debtToEquity"`
+	FreeCashflow            *PriceValue `json:"freeCashflow"`
+	FinancialCurrency       string      `json:"financialCurrency"`
+}
+
+// YahooCalendarEvents --> Struct to hold the calendarEvents module
+type YahooCalendarEvents struct {
+	Earnings struct {
+		EarningsDate    []PriceValue `json:"earningsDate"`
+		EarningsAverage *PriceValue  `json:"earningsAverage"`
+		EarningsLow     *PriceValue  `json:"earningsLow"`
+		EarningsHigh    *PriceValue  `json:"earningsHigh"`
+		RevenueAverage  *PriceValue  `json:"revenueAverage"`
+	} `json:"earnings"`
+	ExDividendDate *PriceValue `json:"exDividendDate"`
+	DividendDate   *PriceValue `json:"dividendDate"`
+}
+
+// YahooAssetProfile --> Struct to hold the assetProfile module
+type YahooAssetProfile struct {
+	Sector              string `json:"sector"`
+	Industry            string `json:"industry"`
+	Country             string `json:"country"`
+	Website             string `json:"website"`
+	FullTimeEmployees   int64  `json:"fullTimeEmployees"`
+	LongBusinessSummary string `json:"longBusinessSummary"`
+}
+
+// NextEarningsDate returns the first upcoming earnings date, Yahoo reports a range when the date isn't confirmed yet
+func (f Fundamentals) NextEarningsDate() (time.Time, bool) {
+	for _, date := range f.CalendarEvents.Earnings.EarningsDate {
+		if date.Raw > 0 {
+			return time.Unix(int64(date.Raw), 0).UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// PE returns the trailing price/earnings ratio, falling back to the forward one
+func (f Fundamentals) PE() *PriceValue {
+	if f.SummaryDetail.TrailingPE != nil && f.SummaryDetail.TrailingPE.Fmt != "" {
+		return f.SummaryDetail.TrailingPE
+	}
+	return f.SummaryDetail.ForwardPE
+}
+
+// Beta returns the beta of the summaryDetail module, falling back to the key statistics
+func (f Fundamentals) Beta() *PriceValue {
+	if f.SummaryDetail.Beta != nil && f.SummaryDetail.Beta.Fmt != "" {
+		return f.SummaryDetail.Beta
+	}
+	return f.DefaultKeyStatistics.Beta
+}
+
+// GetFundamentals fetches the fundamentals modules for a given ticker
+func (i *Information) GetFundamentals(ctx context.Context, symbol string) (Fundamentals, error) {
+	params := url.Values{}
+	params.Add("modules", strings.Join(FundamentalsModules, ","))
+
+	endpoint := fmt.Sprintf("%s/v10/finance/quoteSummary/%s", BASE_URL, symbol)
+
+	resp, err := i.client.Get(ctx, endpoint, params)
+	if err != nil {
+		slog.Error("Failed to get ticker fundamentals", "err", err)
+		return Fundamentals{}, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Fundamentals{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var fundamentalsResponse YahooFundamentalsResponse
+	if err := json.Unmarshal(bodyBytes, &fundamentalsResponse); err != nil {
+		return Fundamentals{}, fmt.Errorf("failed to decode fundamentals JSON: %w", err)
+	}
+
+	if len(fundamentalsResponse.QuoteSummary.Result) == 0 {
+		return Fundamentals{}, &UnknownSymbolError{Symbol: symbol}
+	}
+	return fundamentalsResponse.QuoteSummary.Result[0], nil
+}
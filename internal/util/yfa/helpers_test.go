@@ -0,0 +1,42 @@
+package yfa
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fastRetry keeps the retry backoff of the test clients short
+var fastRetry = RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// useClient points the shared client at the options for the duration of the test
+func useClient(t *testing.T, opts ...ClientOption) {
+	t.Helper()
+	baseURL, cookieURL, previous := BASE_URL, COOKIE_URL, instance
+	t.Cleanup(func() {
+		BASE_URL, COOKIE_URL, instance = baseURL, cookieURL, previous
+	})
+	opts = append([]ClientOption{WithRetryConfig(fastRetry), WithRateLimit(RateLimit{Rate: 1000, Burst: 1000}, nil)}, opts...)
+	ConfigureSharedClient(opts...)
+}
+
+// serveYahoo starts a server answering the cookie and crumb requests and sending the rest to handler
+func serveYahoo(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/test/getcrumb", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("crumb"))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			return
+		}
+		handler(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	useClient(t)
+	SetBaseURL(srv.URL)
+}
@@ -1,6 +1,7 @@
 package yfa
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -23,6 +24,7 @@ type YahooChart struct {
 type YahooHistoryResult struct {
 	Meta       YahooMeta      `json:"meta"`
 	Timestamp  []int64        `json:"timestamp"`
+	Events     YahooEvents    `json:"events"`
 	Indicators YahooIndicator `json:"indicators"`
 }
 
@@ -65,23 +67,38 @@ type YahooTradingPeriod struct {
 }
 
 type YahooIndicator struct {
-	Quote []YahooQuote `json:"quote"`
+	Quote    []YahooQuote    `json:"quote"`
+	AdjClose []YahooAdjClose `json:"adjclose"`
 }
 
+// YahooAdjClose holds the close adjusted for splits and dividends, only reported for daily and longer intervals
+type YahooAdjClose struct {
+	AdjClose []*float64 `json:"adjclose"`
+}
+
+// YahooQuote holds the OHLCV arrays of a chart response.
+// Yahoo reports bars without trades as null, so every value is nullable.
 type YahooQuote struct {
-	Open   []float64 `json:"open"`
-	High   []float64 `json:"high"`
-	Low    []float64 `json:"low"`
-	Close  []float64 `json:"close"`
-	Volume []int64   `json:"volume"`
+	Open   []*float64 `json:"open"`
+	High   []*float64 `json:"high"`
+	Low    []*float64 `json:"low"`
+	Close  []*float64 `json:"close"`
+	Volume []*int64   `json:"volume"`
 }
 
 type PriceData struct {
-	Open   float64
-	High   float64
-	Low    float64
-	Close  float64
-	Volume int64
+	Open     float64
+	High     float64
+	Low      float64
+	Close    float64
+	AdjClose float64
+	Volume   int64
+}
+
+// Valid reports whether the bar carries a price.
+// Bars Yahoo reports as null are left out of the history, but rows from other sources can still be empty.
+func (p PriceData) Valid() bool {
+	return p.Close > 0
 }
 
 type HistoryQuery struct {
@@ -131,7 +148,7 @@ func (h *History) SetQuery(query HistoryQuery) {
 
 // returns the price/volume history of the given symbol as a YahooHistoryResponse
 // If you want to adjust the query range change h.query.Range = "6mo" for 6 month
-func (h *History) GetHistory(symbol string) (YahooHistoryRespose, error) {
+func (h *History) GetHistory(ctx context.Context, symbol string) (YahooHistoryRespose, error) {
 	h.query.SetDefault()
 
 	params := url.Values{}
@@ -142,9 +159,10 @@ func (h *History) GetHistory(symbol string) (YahooHistoryRespose, error) {
 	params.Add("period1", h.query.Start)
 	params.Add("period2", h.query.End)
 	params.Add("includePrePost", "true")
+	params.Add("events", "div,splits")
 
 	endpoint := fmt.Sprintf("%s/v8/finance/chart/%s", BASE_URL, symbol)
-	resp, err := h.client.Get(endpoint, params)
+	resp, err := h.client.Get(ctx, endpoint, params)
 	if err != nil {
 		slog.Error("Failed to get history", "err", err)
 		return YahooHistoryRespose{}, err
@@ -166,33 +184,118 @@ func (h *History) GetHistory(symbol string) (YahooHistoryRespose, error) {
 	return historyResponse, nil
 }
 
+// transformData converts the chart response into PriceData keyed by date in the exchange's timezone.
+// Bars with a null open, high, low or close are skipped, leaving a gap instead of a zero price.
 func (h *History) transformData(data YahooHistoryRespose) map[string]PriceData {
 	d := make(map[string]PriceData)
+	if len(data.Chart.Result) == 0 || len(data.Chart.Result[0].Indicators.Quote) == 0 {
+		return d
+	}
+	quote := data.Chart.Result[0].Indicators.Quote[0]
+	loc := exchangeLocation(data.Chart.Result[0].Meta)
+
 	for i, result := range data.Chart.Result[0].Timestamp {
-		t := time.Unix(result, 0)
+		open, high, low, closing := valueAt(quote.Open, i), valueAt(quote.High, i), valueAt(quote.Low, i), valueAt(quote.Close, i)
+		if open == nil || high == nil || low == nil || closing == nil {
+			continue
+		}
+
+		t := time.Unix(result, 0).In(loc)
 		var key string
 		if strings.HasSuffix(h.query.Interval, "d") || strings.HasSuffix(h.query.Interval, "wk") || strings.HasSuffix(h.query.Interval, "mo") {
 			key = t.Format("2006-01-02")
 		} else {
 			key = t.Format("2006-01-02 15:04:05")
 		}
+
+		var volume int64
+		if v := valueAt(quote.Volume, i); v != nil {
+			volume = *v
+		}
+		adjClose := *closing
+		if len(data.Chart.Result[0].Indicators.AdjClose) > 0 {
+			if v := valueAt(data.Chart.Result[0].Indicators.AdjClose[0].AdjClose, i); v != nil {
+				adjClose = *v
+			}
+		}
 		d[key] = PriceData{
-			Open:   data.Chart.Result[0].Indicators.Quote[0].Open[i],
-			High:   data.Chart.Result[0].Indicators.Quote[0].High[i],
-			Low:    data.Chart.Result[0].Indicators.Quote[0].Low[i],
-			Close:  data.Chart.Result[0].Indicators.Quote[0].Close[i],
-			Volume: data.Chart.Result[0].Indicators.Quote[0].Volume[i],
+			Open:     *open,
+			High:     *high,
+			Low:      *low,
+			Close:    *closing,
+			AdjClose: adjClose,
+			Volume:   volume,
 		}
 	}
 	return d
 }
 
+// exchangeLocation returns the timezone of the exchange, falling back to its GMT offset
+func exchangeLocation(meta YahooMeta) *time.Location {
+	if meta.ExchangeTimezoneName != "" {
+		if loc, err := time.LoadLocation(meta.ExchangeTimezoneName); err == nil {
+			return loc
+		}
+	}
+	name := meta.Timezone
+	if name == "" {
+		name = "UTC"
+	}
+	return time.FixedZone(name, meta.GmtOffset)
+}
+
+// ParseDateKey parses the date part of a history key into the UTC midnight dates are stored as
+func ParseDateKey(key string) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", key[:min(len(key), 10)], time.UTC)
+}
+
+// NormalizeDate returns the calendar date of t as UTC midnight, the form dates are stored as
+func NormalizeDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// valueAt returns the value at index i, or nil when it is null or missing
+func valueAt[T any](values []*T, i int) *T {
+	if i >= len(values) {
+		return nil
+	}
+	return values[i]
+}
+
+// ChartData holds the price history and the corporate actions of a single chart response
+type ChartData struct {
+	History map[string]PriceData
+	Actions []CorporateAction
+}
+
 type PriceHistory struct {
 	Daily  map[string]PriceData
 	Yearly map[string]PriceData
 }
 
-func FetchHistory(ticker *Ticker) (map[string]PriceData, error) {
+func FetchHistory(ctx context.Context, provider MarketDataProvider, symbol string) (map[string]PriceData, error) {
+	hist, err := provider.History(ctx, symbol, yearQuery())
+
+	if err != nil {
+		slog.Error("Error fetching history", slog.Any("err", err))
+	}
+
+	return hist, err
+}
+
+// FetchChart returns the history and the dividends and splits over the same year FetchHistory covers in one request
+func FetchChart(ctx context.Context, provider MarketDataProvider, symbol string) (ChartData, error) {
+	chart, err := provider.Chart(ctx, symbol, yearQuery())
+
+	if err != nil {
+		slog.Error("Error fetching chart", slog.Any("err", err))
+	}
+
+	return chart, err
+}
+
+// yearQuery returns the daily history query for the last year, with its start and end shifted off weekends
+func yearQuery() HistoryQuery {
 	end := time.Now()
 	start := end.AddDate(-1, 0, 0)
 
@@ -209,15 +312,9 @@ func FetchHistory(ticker *Ticker) (map[string]PriceData, error) {
 		end = end.AddDate(0, 0, -2)
 	}
 
-	hist, err := ticker.History(HistoryQuery{
+	return HistoryQuery{
 		Start:    start.Format("2006-01-02"),
 		End:      fmt.Sprintf("%d", end.Unix()),
 		Interval: "1d",
-	})
-
-	if err != nil {
-		slog.Error("Error fetching history", slog.Any("err", err))
 	}
-
-	return hist, err
 }
@@ -1,6 +1,7 @@
 package yfa
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -83,7 +84,7 @@ func newInformation() *Information {
 }
 
 // GetInfo fetches metadata information for a given ticker
-func (i *Information) GetInfo(symbol string) (YahooTickerInfo, error) {
+func (i *Information) GetInfo(ctx context.Context, symbol string) (YahooTickerInfo, error) {
 	// Prepare URL parameters to request the "price" module
 	params := url.Values{}
 	params.Add("modules", "price")
@@ -92,7 +93,7 @@ func (i *Information) GetInfo(symbol string) (YahooTickerInfo, error) {
 	endpoint := fmt.Sprintf("%s/v10/finance/quoteSummary/%s", BASE_URL, symbol)
 
 	// Make the HTTP GET request using the client
-	resp, err := i.client.Get(endpoint, params)
+	resp, err := i.client.Get(ctx, endpoint, params)
 	if err != nil {
 		slog.Error("Failed to get ticker info", "err", err)
 		return YahooTickerInfo{}, err
@@ -112,7 +113,7 @@ func (i *Information) GetInfo(symbol string) (YahooTickerInfo, error) {
 
 	// Check if the result array is empty
 	if len(infoResponse.QuoteSummary.Result) == 0 {
-		return YahooTickerInfo{}, fmt.Errorf("no info found for symbol: %s", symbol)
+		return YahooTickerInfo{}, &UnknownSymbolError{Symbol: symbol}
 	}
 
 	// Return the ticker price information
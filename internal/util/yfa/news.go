@@ -0,0 +1,99 @@
+package yfa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewsCount is the maximum number of headlines News returns
+var NewsCount = 10
+
+// YahooNewsItem --> Struct to hold a single news item of the search endpoint
+type YahooNewsItem struct {
+	UUID                string   `json:"uuid"`
+	Title               string   `json:"title"`
+	Publisher           string   `json:"publisher"`
+	Link                string   `json:"link"`
+	ProviderPublishTime int64    `json:"providerPublishTime"`
+	Type                string   `json:"type"`
+	RelatedTickers      []string `json:"relatedTickers"`
+}
+
+// NewsItem is a headline about a symbol
+type NewsItem struct {
+	ID        string
+	Title     string
+	Publisher string
+	Link      string
+	Published time.Time
+}
+
+// GetNews fetches the latest headlines for the symbol through the search endpoint
+func (s *Search) GetNews(ctx context.Context, symbol string) (YahooSearchResponse, error) {
+	params := url.Values{}
+	params.Add("q", symbol)
+	params.Add("quotesCount", "0")
+	params.Add("newsCount", strconv.Itoa(NewsCount))
+	params.Add("listsCount", "0")
+	params.Add("enableFuzzyQuery", "false")
+
+	endpoint := fmt.Sprintf("%s/v1/finance/search", BASE_URL)
+	resp, err := s.client.Get(ctx, endpoint, params)
+	if err != nil {
+		slog.Error("Failed to get news", "err", err)
+		return YahooSearchResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return YahooSearchResponse{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var searchResponse YahooSearchResponse
+	if err := json.Unmarshal(bodyBytes, &searchResponse); err != nil {
+		return YahooSearchResponse{}, fmt.Errorf("failed to decode news JSON: %w", err)
+	}
+	return searchResponse, nil
+}
+
+// News returns the latest headlines about the symbol, newest first.
+// The search matches on text, so items that list related tickers without the symbol are left out.
+func (s *Search) News(ctx context.Context, symbol string) ([]NewsItem, error) {
+	response, err := s.GetNews(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]NewsItem, 0, len(response.News))
+	for _, news := range response.News {
+		if news.UUID == "" || news.Title == "" {
+			continue
+		}
+		if len(news.RelatedTickers) > 0 && !slices.ContainsFunc(news.RelatedTickers, func(ticker string) bool {
+			return strings.EqualFold(ticker, symbol)
+		}) {
+			continue
+		}
+		items = append(items, NewsItem{
+			ID:        news.UUID,
+			Title:     news.Title,
+			Publisher: news.Publisher,
+			Link:      news.Link,
+			Published: time.Unix(news.ProviderPublishTime, 0).UTC(),
+		})
+	}
+
+	slices.SortFunc(items, func(a, b NewsItem) int {
+		return b.Published.Compare(a.Published)
+	})
+	return items, nil
+}
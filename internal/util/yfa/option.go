@@ -1,6 +1,7 @@
 package yfa
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -173,52 +174,57 @@ func newOption() *Option {
 	return &Option{client: getClient()}
 }
 
-func (o *Option) GetOptionChain(symbol string) YahooOptionResponse {
-	endpoint := fmt.Sprintf("%s/v7/finance/options/%s", BASE_URL, symbol)
-	resp, err := o.client.Get(endpoint, url.Values{})
-	if err != nil {
-		slog.Error("Failed to get option chain", "err", err)
-		return YahooOptionResponse{}
-	}
-	defer resp.Body.Close()
-
-	var optionResponse YahooOptionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&optionResponse); err != nil {
-		slog.Error("Failed to decode option data JSON response", "err", err)
-	}
-
-	return optionResponse
+// GetOptionChain fetches the nearest option chain for a given ticker
+func (o *Option) GetOptionChain(ctx context.Context, symbol string) (YahooOptionResponse, error) {
+	return o.getOptionChain(ctx, symbol, url.Values{})
 }
 
-func (o *Option) GetOptionChainByExpiration(symbol string, expirationDate string) YahooOptionResponse {
+// GetOptionChainByExpiration fetches the option chain expiring on the date (YYYY-MM-DD) for a given ticker
+func (o *Option) GetOptionChainByExpiration(ctx context.Context, symbol string, expirationDate string) (YahooOptionResponse, error) {
 	t, err := time.Parse("2006-01-02", expirationDate)
 	if err != nil {
-		slog.Error("Failed to parse expiration date", "err", err)
-		return YahooOptionResponse{}
+		return YahooOptionResponse{}, fmt.Errorf("failed to parse expiration date: %w", err)
 	}
-	endpoint := fmt.Sprintf("%s/v7/finance/options/%s", BASE_URL, symbol)
 	params := url.Values{}
 	params.Add("date", fmt.Sprintf("%d", t.Unix()))
-	resp, err := o.client.Get(endpoint, params)
+	return o.getOptionChain(ctx, symbol, params)
+}
+
+// getOptionChain fetches and decodes the options endpoint, an empty result means Yahoo doesn't know the symbol
+func (o *Option) getOptionChain(ctx context.Context, symbol string, params url.Values) (YahooOptionResponse, error) {
+	endpoint := fmt.Sprintf("%s/v7/finance/options/%s", BASE_URL, symbol)
+	resp, err := o.client.Get(ctx, endpoint, params)
 	if err != nil {
-		slog.Error("Failed to get option chain by expiration", "err", err)
-		return YahooOptionResponse{}
+		slog.Error("Failed to get option chain", "err", err)
+		return YahooOptionResponse{}, err
 	}
 	defer resp.Body.Close()
 
 	var optionResponse YahooOptionResponse
 	if err := json.NewDecoder(resp.Body).Decode(&optionResponse); err != nil {
-		slog.Error("Failed to decode option data JSON response", "err", err)
+		return YahooOptionResponse{}, fmt.Errorf("failed to decode option data JSON: %w", err)
 	}
 
-	return optionResponse
+	if len(optionResponse.OptionChain.Result) == 0 {
+		return YahooOptionResponse{}, &UnknownSymbolError{Symbol: symbol}
+	}
+	return optionResponse, nil
 }
 
-func (o *Option) transformData(data YahooOptionResponse) OptionData {
-	date := time.Unix(data.OptionChain.Result[0].Options[0].ExpirationDate, 0).Format("2006-01-02")
+// transformData converts the first chain of the response, returning ErrNoOptions when there is none
+func (o *Option) transformData(data YahooOptionResponse) (OptionData, error) {
+	if len(data.OptionChain.Result) == 0 {
+		return OptionData{}, ErrNoOptions
+	}
+	result := data.OptionChain.Result[0]
+	if len(result.Options) == 0 || len(result.Options[0].Calls)+len(result.Options[0].Puts) == 0 {
+		return OptionData{}, fmt.Errorf("%w for %s", ErrNoOptions, result.UnderlyingSymbol)
+	}
+
+	date := time.Unix(result.Options[0].ExpirationDate, 0).UTC().Format("2006-01-02")
 	var calls []OptionDetail
 	var puts []OptionDetail
-	for _, call := range data.OptionChain.Result[0].Options[0].Calls {
+	for _, call := range result.Options[0].Calls {
 		calls = append(calls, OptionDetail{
 			ContractSymbol:    call.ContractSymbol,
 			Strike:            call.Strike,
@@ -231,13 +237,13 @@ func (o *Option) transformData(data YahooOptionResponse) OptionData {
 			Bid:               call.Bid,
 			Ask:               call.Ask,
 			ContractSize:      call.ContractSize,
-			Expiration:        time.Unix(call.Expiration, 0).Format("2006-01-02"),
+			Expiration:        time.Unix(call.Expiration, 0).UTC().Format("2006-01-02"),
 			LastTradeDate:     time.Unix(call.LastTradeDate, 0).Format("2006-01-02"),
 			ImpliedVolatility: call.ImpliedVolatility,
 			InTheMoney:        call.InTheMoney,
 		})
 	}
-	for _, put := range data.OptionChain.Result[0].Options[0].Puts {
+	for _, put := range result.Options[0].Puts {
 		puts = append(puts, OptionDetail{
 			ContractSymbol:    put.ContractSymbol,
 			Strike:            put.Strike,
@@ -250,7 +256,7 @@ func (o *Option) transformData(data YahooOptionResponse) OptionData {
 			Bid:               put.Bid,
 			Ask:               put.Ask,
 			ContractSize:      put.ContractSize,
-			Expiration:        time.Unix(put.Expiration, 0).Format("2006-01-02"),
+			Expiration:        time.Unix(put.Expiration, 0).UTC().Format("2006-01-02"),
 			LastTradeDate:     time.Unix(put.LastTradeDate, 0).Format("2006-01-02"),
 			ImpliedVolatility: put.ImpliedVolatility,
 			InTheMoney:        put.InTheMoney,
@@ -258,17 +264,25 @@ func (o *Option) transformData(data YahooOptionResponse) OptionData {
 	}
 	return OptionData{
 		ExpirationDate: date,
-		HasMiniOptions: data.OptionChain.Result[0].HasMiniOptions,
+		HasMiniOptions: result.HasMiniOptions,
 		Calls:          calls,
 		Puts:           puts,
-	}
+	}, nil
 }
 
-func (o *Option) GetExpirationDates(symbol string) []string {
-	optionChain := o.GetOptionChain(symbol)
+// GetExpirationDates fetches the option expiration dates (YYYY-MM-DD) for a given ticker
+func (o *Option) GetExpirationDates(ctx context.Context, symbol string) ([]string, error) {
+	optionChain, err := o.GetOptionChain(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
 	var expirationDates []string
 	for _, date := range optionChain.OptionChain.Result[0].ExpirationDates {
-		expirationDates = append(expirationDates, time.Unix(date, 0).Format("2006-01-02"))
+		expirationDates = append(expirationDates, time.Unix(date, 0).UTC().Format("2006-01-02"))
+	}
+	if len(expirationDates) == 0 {
+		return nil, fmt.Errorf("%w for %s", ErrNoOptions, symbol)
 	}
-	return expirationDates
+	return expirationDates, nil
 }
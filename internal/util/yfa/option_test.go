@@ -0,0 +1,147 @@
+package yfa
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"testing"
+)
+
+// serveOptionChains answers the options endpoint with the recorded chains in testdata/options,
+// THROTTLED is answered with a 429 every time
+func serveOptionChains(t *testing.T) {
+	t.Helper()
+	chains := map[string]string{
+		"AAPL":    "testdata/options/aapl.json",
+		"NOPE":    "testdata/options/unknown.json",
+		"VWRL.AS": "testdata/options/no_options.json",
+	}
+	serveYahoo(t, func(w http.ResponseWriter, r *http.Request) {
+		symbol := path.Base(r.URL.Path)
+		if symbol == "THROTTLED" {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		body, err := os.ReadFile(chains[symbol])
+		if err != nil {
+			t.Errorf("no recorded chain for %s", symbol)
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(body)
+	})
+}
+
+func TestOptionChain(t *testing.T) {
+	serveOptionChains(t)
+
+	chain, err := NewTicker("AAPL").OptionChain(context.Background())
+	if err != nil {
+		t.Fatalf("OptionChain: %v", err)
+	}
+	if chain.ExpirationDate != "2024-10-18" {
+		t.Errorf("ExpirationDate = %s, want 2024-10-18", chain.ExpirationDate)
+	}
+	if len(chain.Calls) != 2 || len(chain.Puts) != 1 {
+		t.Fatalf("got %d calls and %d puts, want 2 and 1", len(chain.Calls), len(chain.Puts))
+	}
+
+	call := chain.Calls[0]
+	want := OptionDetail{
+		ContractSymbol:    "AAPL241018C00220000",
+		Strike:            220,
+		Currency:          "USD",
+		LastPrice:         6.45,
+		Change:            0.35,
+		PercentChange:     5.74,
+		Volume:            8123,
+		OpenInterest:      15230,
+		Bid:               6.35,
+		Ask:               6.5,
+		ContractSize:      "REGULAR",
+		Expiration:        "2024-10-18",
+		LastTradeDate:     call.LastTradeDate,
+		ImpliedVolatility: 0.2419,
+		InTheMoney:        true,
+	}
+	if call != want {
+		t.Errorf("call = %+v\nwant %+v", call, want)
+	}
+	if chain.Puts[0].ContractSymbol != "AAPL241018P00225000" || chain.Puts[0].InTheMoney {
+		t.Errorf("put = %+v", chain.Puts[0])
+	}
+
+	dates, err := NewTicker("AAPL").ExpirationDates(context.Background())
+	if err != nil {
+		t.Fatalf("ExpirationDates: %v", err)
+	}
+	wantDates := []string{"2024-10-18", "2024-10-25", "2024-11-01"}
+	if len(dates) != len(wantDates) {
+		t.Fatalf("ExpirationDates = %v, want %v", dates, wantDates)
+	}
+	for i := range wantDates {
+		if dates[i] != wantDates[i] {
+			t.Errorf("ExpirationDates = %v, want %v", dates, wantDates)
+			break
+		}
+	}
+}
+
+func TestOptionChainErrors(t *testing.T) {
+	serveOptionChains(t)
+
+	tests := []struct {
+		name   string
+		symbol string
+		want   error
+	}{
+		{name: "empty result", symbol: "NOPE", want: ErrUnknownSymbol},
+		{name: "no options", symbol: "VWRL.AS", want: ErrNoOptions},
+		{name: "persistent 429", symbol: "THROTTLED", want: ErrThrottled},
+	}
+
+	fetches := []struct {
+		name  string
+		fetch func(ticker *Ticker) error
+	}{
+		{"OptionChain", func(ticker *Ticker) error {
+			_, err := ticker.OptionChain(context.Background())
+			return err
+		}},
+		{"OptionChainByExpiration", func(ticker *Ticker) error {
+			_, err := ticker.OptionChainByExpiration(context.Background(), "2024-10-18")
+			return err
+		}},
+		{"ExpirationDates", func(ticker *Ticker) error {
+			_, err := ticker.ExpirationDates(context.Background())
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		for _, f := range fetches {
+			t.Run(tt.name+"/"+f.name, func(t *testing.T) {
+				err := f.fetch(NewTicker(tt.symbol))
+				if !errors.Is(err, tt.want) {
+					t.Fatalf("err = %v, want %v", err, tt.want)
+				}
+
+				switch tt.want {
+				case ErrUnknownSymbol:
+					var unknown *UnknownSymbolError
+					if !errors.As(err, &unknown) || unknown.Symbol != tt.symbol {
+						t.Errorf("err = %#v, want an UnknownSymbolError for %s", err, tt.symbol)
+					}
+				case ErrThrottled:
+					var throttled *ThrottledError
+					if !errors.As(err, &throttled) || throttled.Endpoint != "options" {
+						t.Errorf("err = %#v, want a ThrottledError for the options endpoint", err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package yfa
+
+import "context"
+
+// MarketDataProvider is a source of market data for a symbol.
+// The Yahoo Finance implementation is returned by NewYahooProvider, other sources
+// (or fakes) can be swapped in by implementing this interface.
+type MarketDataProvider interface {
+	// Quote returns the latest PriceData for the symbol.
+	Quote(ctx context.Context, symbol string) (PriceData, error)
+	// Info returns the ticker information for the symbol.
+	Info(ctx context.Context, symbol string) (YahooTickerInfo, error)
+	// Fundamentals returns the valuation, dividend, earnings and profile data for the symbol.
+	Fundamentals(ctx context.Context, symbol string) (Fundamentals, error)
+	// Quotes returns the ticker information for many symbols in as few requests as possible, keyed by symbol.
+	Quotes(ctx context.Context, symbols []string) (map[string]YahooTickerInfo, error)
+	// History returns the historical price data for the symbol based on the provided query.
+	History(ctx context.Context, symbol string, query HistoryQuery) (map[string]PriceData, error)
+	// Chart returns the historical price data together with the dividends and splits within the range of the provided query.
+	Chart(ctx context.Context, symbol string, query HistoryQuery) (ChartData, error)
+	// OptionChain returns the nearest option chain for the symbol.
+	OptionChain(ctx context.Context, symbol string) (OptionData, error)
+	// OptionChainByExpiration returns the option chain for the symbol on a specific expiration date.
+	OptionChainByExpiration(ctx context.Context, symbol, expiration string) (OptionData, error)
+	// ExpirationDates returns the available option expiration dates for the symbol.
+	ExpirationDates(ctx context.Context, symbol string) ([]string, error)
+	// Search returns the tickers matching the query, best match first.
+	Search(ctx context.Context, query string) ([]SearchResult, error)
+	// News returns the latest headlines about the symbol, newest first.
+	News(ctx context.Context, symbol string) ([]NewsItem, error)
+}
+
+// YahooProvider implements MarketDataProvider on top of the Yahoo Finance API.
+type YahooProvider struct{}
+
+// NewYahooProvider creates a MarketDataProvider backed by Yahoo Finance.
+func NewYahooProvider() *YahooProvider {
+	return &YahooProvider{}
+}
+
+func (y *YahooProvider) Quote(ctx context.Context, symbol string) (PriceData, error) {
+	return NewTicker(symbol).Quote(ctx)
+}
+
+func (y *YahooProvider) Info(ctx context.Context, symbol string) (YahooTickerInfo, error) {
+	return NewTicker(symbol).Info(ctx)
+}
+
+func (y *YahooProvider) Fundamentals(ctx context.Context, symbol string) (Fundamentals, error) {
+	return NewTicker(symbol).Fundamentals(ctx)
+}
+
+func (y *YahooProvider) Quotes(ctx context.Context, symbols []string) (map[string]YahooTickerInfo, error) {
+	return Quotes(ctx, symbols)
+}
+
+func (y *YahooProvider) History(ctx context.Context, symbol string, query HistoryQuery) (map[string]PriceData, error) {
+	return NewTicker(symbol).History(ctx, query)
+}
+
+func (y *YahooProvider) Chart(ctx context.Context, symbol string, query HistoryQuery) (ChartData, error) {
+	return NewTicker(symbol).Chart(ctx, query)
+}
+
+func (y *YahooProvider) OptionChain(ctx context.Context, symbol string) (OptionData, error) {
+	return NewTicker(symbol).OptionChain(ctx)
+}
+
+func (y *YahooProvider) OptionChainByExpiration(ctx context.Context, symbol, expiration string) (OptionData, error) {
+	return NewTicker(symbol).OptionChainByExpiration(ctx, expiration)
+}
+
+func (y *YahooProvider) ExpirationDates(ctx context.Context, symbol string) ([]string, error) {
+	return NewTicker(symbol).ExpirationDates(ctx)
+}
+
+func (y *YahooProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	return newSearch().Search(ctx, query)
+}
+
+func (y *YahooProvider) News(ctx context.Context, symbol string) ([]NewsItem, error) {
+	return newSearch().News(ctx, symbol)
+}
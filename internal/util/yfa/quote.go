@@ -0,0 +1,140 @@
+package yfa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// QuoteBatchSize is the maximum number of symbols sent in a single quote request
+var QuoteBatchSize = 50
+
+// YahooQuoteResponse --> Struct to hold the result from the Yahoo Finance multi-symbol quote endpoint
+type YahooQuoteResponse struct {
+	QuoteResponse struct {
+		Result []YahooOptionQuote `json:"result"`
+		Error  any                `json:"error"`
+	} `json:"quoteResponse"`
+}
+
+// currencySymbols maps the most common currencies to the symbol the quoteSummary price module reports
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"GBp": "£",
+	"JPY": "¥",
+	"CNY": "¥",
+	"INR": "₹",
+	"CAD": "$",
+	"AUD": "$",
+	"CHF": "CHF",
+}
+
+// BatchQuote holds the HTTP client
+type BatchQuote struct {
+	client *Client
+}
+
+// newBatchQuote initializes the BatchQuote struct with an HTTP client
+func newBatchQuote() *BatchQuote {
+	return &BatchQuote{client: getClient()}
+}
+
+// GetQuotes fetches the quotes for all symbols, chunked into requests of QuoteBatchSize symbols
+func (b *BatchQuote) GetQuotes(ctx context.Context, symbols []string) ([]YahooOptionQuote, error) {
+	var quotes []YahooOptionQuote
+	for chunk := range slices.Chunk(symbols, QuoteBatchSize) {
+		params := url.Values{}
+		params.Add("symbols", strings.Join(chunk, ","))
+
+		endpoint := fmt.Sprintf("%s/v7/finance/quote", BASE_URL)
+		resp, err := b.client.Get(ctx, endpoint, params)
+		if err != nil {
+			slog.Error("Failed to get quotes", "err", err)
+			return nil, err
+		}
+
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("quote request failed: %s", resp.Status)
+		}
+
+		var quoteResponse YahooQuoteResponse
+		if err := json.Unmarshal(bodyBytes, &quoteResponse); err != nil {
+			return nil, fmt.Errorf("failed to decode quote JSON: %w", err)
+		}
+		if quoteResponse.QuoteResponse.Error != nil {
+			return nil, fmt.Errorf("yahoo finance returned a quote error: %v", quoteResponse.QuoteResponse.Error)
+		}
+		quotes = append(quotes, quoteResponse.QuoteResponse.Result...)
+	}
+	return quotes, nil
+}
+
+// Quotes fetches the ticker information for many symbols at once, keyed by symbol.
+// Symbols Yahoo doesn't know are missing from the result.
+func Quotes(ctx context.Context, symbols []string) (map[string]YahooTickerInfo, error) {
+	quotes, err := newBatchQuote().GetQuotes(ctx, symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make(map[string]YahooTickerInfo, len(quotes))
+	for _, quote := range quotes {
+		infos[quote.Symbol] = quote.toTickerInfo()
+	}
+	return infos, nil
+}
+
+// toTickerInfo converts a quote into the shape returned by the quoteSummary price module
+func (q YahooOptionQuote) toTickerInfo() YahooTickerInfo {
+	decimals := q.PriceHint
+	if decimals == 0 {
+		decimals = 2
+	}
+	price := func(v float64) *PriceValue {
+		return &PriceValue{Raw: v, Fmt: strconv.FormatFloat(v, 'f', decimals, 64)}
+	}
+	// the quote endpoint reports percentages, the price module fractions
+	percent := func(v float64) *PriceValue {
+		return &PriceValue{Raw: v / 100, Fmt: fmt.Sprintf("%.2f%%", v)}
+	}
+
+	return YahooTickerInfo{
+		Symbol:                     q.Symbol,
+		ShortName:                  q.ShortName,
+		LongName:                   q.LongName,
+		Currency:                   q.Currency,
+		CurrencySymbol:             currencySymbols[q.Currency],
+		Exchange:                   q.Exchange,
+		ExchangeName:               q.FullExchangeName,
+		ExchangeDataDelayedBy:      q.ExchangeDataDelayedBy,
+		MarketState:                q.MarketState,
+		QuoteType:                  q.QuoteType,
+		QuoteSourceName:            q.QuoteSourceName,
+		RegularMarketTime:          q.RegularMarketTime,
+		RegularMarketPrice:         price(q.RegularMarketPrice),
+		RegularMarketChange:        price(q.RegularMarketChange),
+		RegularMarketChangePercent: percent(q.RegularMarketChangePercent),
+		RegularMarketDayHigh:       price(q.RegularMarketDayHigh),
+		RegularMarketDayLow:        price(q.RegularMarketDayLow),
+		RegularMarketOpen:          price(q.RegularMarketOpen),
+		RegularMarketPreviousClose: price(q.RegularMarketPreviousClose),
+		PostMarketTime:             q.PostMarketTime,
+		PostMarketPrice:            price(q.PostMarketPrice),
+		PostMarketChange:           price(q.PostMarketChange),
+		PostMarketChangePercent:    percent(q.PostMarketChangePercent),
+	}
+}
@@ -0,0 +1,182 @@
+package yfa
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit describes a token bucket: Rate tokens are added per second up to Burst tokens
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultRateLimit caps the overall request rate of a client
+var DefaultRateLimit = RateLimit{Rate: 4, Burst: 8}
+
+// DefaultEndpointBudgets caps the request rate per Yahoo endpoint, on top of the overall limit.
+// The alert checker polls the batch quote every few seconds and the news checker fetches the headlines
+// of every watched symbol at once, so neither can use up the overall limit on its own.
+var DefaultEndpointBudgets = map[string]RateLimit{
+	"chart":        {Rate: 2, Burst: 5},
+	"quoteSummary": {Rate: 2, Burst: 5},
+	"options":      {Rate: 1, Burst: 2},
+	"search":       {Rate: 2, Burst: 5},
+	"quote":        {Rate: 1, Burst: 5},
+	"news":         {Rate: 1, Burst: 5},
+}
+
+// RateLimitStats holds the limiter metrics of a single endpoint
+type RateLimitStats struct {
+	// Requests is the number of requests that were let through
+	Requests int64
+	// Delayed is the number of requests that had to wait for a token
+	Delayed int64
+	// Cancelled is the number of requests whose context ended while waiting
+	Cancelled int64
+	// Throttled is the number of 429 responses received from Yahoo
+	Throttled int64
+	// WaitTime is the total time spent waiting for tokens
+	WaitTime time.Duration
+}
+
+type tokenBucket struct {
+	mu     sync.Mutex
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	return &tokenBucket{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+}
+
+// reserve takes a token if one is available, otherwise it returns how long until the next one is
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+// wait blocks until a token is taken or the context is done
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil || b.limit.Rate <= 0 {
+		return nil
+	}
+	for {
+		delay := b.reserve()
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// rateLimiter combines the overall bucket with the per-endpoint budgets
+type rateLimiter struct {
+	global    *tokenBucket
+	endpoints map[string]*tokenBucket
+
+	mu    sync.Mutex
+	stats map[string]*RateLimitStats
+}
+
+func newRateLimiter(global RateLimit, budgets map[string]RateLimit) *rateLimiter {
+	r := &rateLimiter{
+		global:    newTokenBucket(global),
+		endpoints: make(map[string]*tokenBucket),
+		stats:     make(map[string]*RateLimitStats),
+	}
+	for endpoint, limit := range budgets {
+		r.endpoints[endpoint] = newTokenBucket(limit)
+	}
+	return r
+}
+
+// Wait queues the caller until both the endpoint budget and the overall limit allow a request
+func (r *rateLimiter) Wait(ctx context.Context, endpoint string) error {
+	start := time.Now()
+
+	err := r.endpoints[endpoint].wait(ctx)
+	if err == nil {
+		err = r.global.wait(ctx)
+	}
+	waited := time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.statsFor(endpoint)
+	stats.WaitTime += waited
+	if waited > time.Millisecond {
+		stats.Delayed++
+	}
+	if err != nil {
+		stats.Cancelled++
+		return err
+	}
+	stats.Requests++
+	return nil
+}
+
+// Throttled records a 429 response for the endpoint
+func (r *rateLimiter) Throttled(endpoint string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statsFor(endpoint).Throttled++
+}
+
+// Stats returns a snapshot of the metrics per endpoint
+func (r *rateLimiter) Stats() map[string]RateLimitStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	snapshot := make(map[string]RateLimitStats, len(r.stats))
+	for endpoint, stats := range r.stats {
+		snapshot[endpoint] = *stats
+	}
+	return snapshot
+}
+
+func (r *rateLimiter) statsFor(endpoint string) *RateLimitStats {
+	stats, ok := r.stats[endpoint]
+	if !ok {
+		stats = &RateLimitStats{}
+		r.stats[endpoint] = stats
+	}
+	return stats
+}
+
+// endpointName maps a Yahoo url like /v8/finance/chart/AAPL to its budget name ("chart").
+// Headlines come from the search endpoint too, a search for news only is budgeted as "news".
+func endpointName(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "other"
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 3 || parts[1] != "finance" {
+		return "other"
+	}
+	if parts[2] == "search" && params.Get("quotesCount") == "0" && params.Get("newsCount") != "0" {
+		return "news"
+	}
+	return parts[2]
+}
@@ -0,0 +1,112 @@
+package yfa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"strconv"
+)
+
+// SearchResultCount is the maximum number of tickers a search returns
+var SearchResultCount = 10
+
+// YahooSearchResponse --> Struct to hold the result from the Yahoo Finance search endpoint
+type YahooSearchResponse struct {
+	Quotes []YahooSearchQuote `json:"quotes"`
+	News   []YahooNewsItem    `json:"news"`
+}
+
+// YahooSearchQuote --> Struct to hold a single ticker matching the search
+type YahooSearchQuote struct {
+	Symbol         string  `json:"symbol"`
+	ShortName      string  `json:"shortname"`
+	LongName       string  `json:"longname"`
+	Exchange       string  `json:"exchange"`
+	ExchangeName   string  `json:"exchDisp"`
+	QuoteType      string  `json:"quoteType"`
+	TypeName       string  `json:"typeDisp"`
+	Score          float64 `json:"score"`
+	IsYahooFinance bool    `json:"isYahooFinance"`
+}
+
+// SearchResult is a ticker matching a search query
+type SearchResult struct {
+	Symbol   string
+	Name     string
+	Exchange string
+	Type     string
+}
+
+// Search holds the HTTP client
+type Search struct {
+	client *Client
+}
+
+// newSearch initializes the Search struct with an HTTP client
+func newSearch() *Search {
+	return &Search{client: getClient()}
+}
+
+// GetSearch fetches the tickers matching the query
+func (s *Search) GetSearch(ctx context.Context, query string) (YahooSearchResponse, error) {
+	params := url.Values{}
+	params.Add("q", query)
+	params.Add("quotesCount", strconv.Itoa(SearchResultCount))
+	params.Add("newsCount", "0")
+	params.Add("listsCount", "0")
+	params.Add("enableFuzzyQuery", "false")
+
+	endpoint := fmt.Sprintf("%s/v1/finance/search", BASE_URL)
+	resp, err := s.client.Get(ctx, endpoint, params)
+	if err != nil {
+		slog.Error("Failed to search", "err", err)
+		return YahooSearchResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return YahooSearchResponse{}, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var searchResponse YahooSearchResponse
+	if err := json.Unmarshal(bodyBytes, &searchResponse); err != nil {
+		return YahooSearchResponse{}, fmt.Errorf("failed to decode search JSON: %w", err)
+	}
+	return searchResponse, nil
+}
+
+// Search returns the tradable tickers matching the query, best match first.
+// Results Yahoo has no quotes for, like private companies, are left out.
+func (s *Search) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	response, err := s.GetSearch(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(response.Quotes))
+	for _, quote := range response.Quotes {
+		if quote.Symbol == "" || !quote.IsYahooFinance {
+			continue
+		}
+
+		name := quote.LongName
+		if name == "" {
+			name = quote.ShortName
+		}
+		exchange := quote.ExchangeName
+		if exchange == "" {
+			exchange = quote.Exchange
+		}
+		results = append(results, SearchResult{
+			Symbol:   quote.Symbol,
+			Name:     name,
+			Exchange: exchange,
+			Type:     quote.TypeName,
+		})
+	}
+	return results, nil
+}
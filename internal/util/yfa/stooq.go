@@ -0,0 +1,369 @@
+package yfa
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultStooqURL is the Stooq CSV download endpoint.
+// {symbol}, {interval}, {start} and {end} are filled in per request, a template without them works too,
+// so a local file server can serve e.g. http://localhost:8000/{symbol}.csv.
+const DefaultStooqURL = "https://stooq.com/q/d/l/?s={symbol}&i={interval}&d1={start}&d2={end}"
+
+// stooqQuoteWindow is how far back Quote and Info look for the latest bars
+const stooqQuoteWindow = 14 * 24 * time.Hour
+
+// StooqProvider implements MarketDataProvider on top of Stooq style daily OHLCV CSV files.
+// It only serves daily, weekly and monthly prices, everything else returns ErrUnsupported.
+type StooqProvider struct {
+	url    string
+	client *http.Client
+}
+
+// NewStooqProvider creates a MarketDataProvider that downloads CSVs from the url template, DefaultStooqURL when empty
+func NewStooqProvider(urlTemplate string) *StooqProvider {
+	if urlTemplate == "" {
+		urlTemplate = DefaultStooqURL
+	}
+	return &StooqProvider{
+		url:    urlTemplate,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// stooqCurrencies maps the market suffix of a Stooq symbol to the currency its prices are in
+var stooqCurrencies = map[string]string{
+	"us": "USD",
+	"de": "EUR",
+	"hk": "HKD",
+}
+
+// stooqCurrency returns the currency of a Stooq symbol, false when its market isn't known
+func stooqCurrency(symbol string) (string, bool) {
+	stooqSymbol := StooqSymbol(symbol)
+	i := strings.LastIndex(stooqSymbol, ".")
+	if i < 0 {
+		return "", false
+	}
+	currency, ok := stooqCurrencies[stooqSymbol[i+1:]]
+	return currency, ok
+}
+
+// StooqSymbol maps a Yahoo symbol to the Stooq one, US listings carry a .us suffix there
+func StooqSymbol(symbol string) string {
+	symbol = strings.ToLower(NormalizeSymbol(symbol))
+	if strings.ContainsAny(symbol, ".^=") {
+		return symbol
+	}
+	return symbol + ".us"
+}
+
+func (s *StooqProvider) Quote(ctx context.Context, symbol string) (PriceData, error) {
+	bars, err := s.latest(ctx, symbol)
+	if err != nil {
+		return PriceData{}, err
+	}
+	return bars[len(bars)-1].price, nil
+}
+
+// Info returns the latest prices of the symbol, ErrUnsupported when the currency of its market isn't known.
+// Stooq doesn't report the currency, so without it the prices couldn't be valued or traded.
+func (s *StooqProvider) Info(ctx context.Context, symbol string) (YahooTickerInfo, error) {
+	currency, ok := stooqCurrency(symbol)
+	if !ok {
+		return YahooTickerInfo{}, fmt.Errorf("stooq currency of %s: %w", symbol, ErrUnsupported)
+	}
+
+	bars, err := s.latest(ctx, symbol)
+	if err != nil {
+		return YahooTickerInfo{}, err
+	}
+
+	price := func(v float64) *PriceValue {
+		return &PriceValue{Raw: v, Fmt: strconv.FormatFloat(v, 'f', 2, 64)}
+	}
+	last := bars[len(bars)-1]
+	previous := last.price.Open
+	if len(bars) > 1 {
+		previous = bars[len(bars)-2].price.Close
+	}
+	change := last.price.Close - previous
+	var changePercent float64
+	if previous != 0 {
+		changePercent = change / previous
+	}
+
+	return YahooTickerInfo{
+		Symbol:                     NormalizeSymbol(symbol),
+		ShortName:                  NormalizeSymbol(symbol),
+		Currency:                   currency,
+		CurrencySymbol:             currencySymbols[currency],
+		QuoteSourceName:            "Stooq",
+		RegularMarketTime:          last.date.Unix(),
+		RegularMarketPrice:         price(last.price.Close),
+		RegularMarketOpen:          price(last.price.Open),
+		RegularMarketDayHigh:       price(last.price.High),
+		RegularMarketDayLow:        price(last.price.Low),
+		RegularMarketPreviousClose: price(previous),
+		RegularMarketChange:        price(change),
+		RegularMarketChangePercent: &PriceValue{Raw: changePercent, Fmt: fmt.Sprintf("%.2f%%", changePercent*100)},
+		RegularMarketVolume:        &PriceValue{Raw: float64(last.price.Volume), Fmt: strconv.FormatInt(last.price.Volume, 10)},
+	}, nil
+}
+
+// Fundamentals isn't available from Stooq
+func (s *StooqProvider) Fundamentals(ctx context.Context, symbol string) (Fundamentals, error) {
+	return Fundamentals{}, ErrUnsupported
+}
+
+// Quotes fetches the symbols one by one, Stooq has no batch endpoint.
+// Symbols Stooq doesn't know, or whose currency isn't known, are missing from the result.
+func (s *StooqProvider) Quotes(ctx context.Context, symbols []string) (map[string]YahooTickerInfo, error) {
+	infos := make(map[string]YahooTickerInfo, len(symbols))
+	for _, symbol := range symbols {
+		info, err := s.Info(ctx, symbol)
+		if errors.Is(err, ErrUnknownSymbol) || errors.Is(err, ErrUnsupported) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos[info.Symbol] = info
+	}
+	return infos, nil
+}
+
+func (s *StooqProvider) History(ctx context.Context, symbol string, query HistoryQuery) (map[string]PriceData, error) {
+	start, end, err := historyWindow(query)
+	if err != nil {
+		return nil, err
+	}
+	bars, err := s.fetch(ctx, symbol, query.Interval, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	history := make(map[string]PriceData, len(bars))
+	for _, bar := range bars {
+		history[bar.date.Format("2006-01-02")] = bar.price
+	}
+	return history, nil
+}
+
+// Chart returns the history without corporate actions, Stooq doesn't report dividends or splits
+func (s *StooqProvider) Chart(ctx context.Context, symbol string, query HistoryQuery) (ChartData, error) {
+	history, err := s.History(ctx, symbol, query)
+	if err != nil {
+		return ChartData{}, err
+	}
+	return ChartData{History: history}, nil
+}
+
+// OptionChain isn't available from Stooq
+func (s *StooqProvider) OptionChain(ctx context.Context, symbol string) (OptionData, error) {
+	return OptionData{}, ErrUnsupported
+}
+
+// OptionChainByExpiration isn't available from Stooq
+func (s *StooqProvider) OptionChainByExpiration(ctx context.Context, symbol, expiration string) (OptionData, error) {
+	return OptionData{}, ErrUnsupported
+}
+
+// ExpirationDates isn't available from Stooq
+func (s *StooqProvider) ExpirationDates(ctx context.Context, symbol string) ([]string, error) {
+	return nil, ErrUnsupported
+}
+
+// Search isn't available from Stooq
+func (s *StooqProvider) Search(ctx context.Context, query string) ([]SearchResult, error) {
+	return nil, ErrUnsupported
+}
+
+// News isn't available from Stooq
+func (s *StooqProvider) News(ctx context.Context, symbol string) ([]NewsItem, error) {
+	return nil, ErrUnsupported
+}
+
+type stooqBar struct {
+	date  time.Time
+	price PriceData
+}
+
+// latest returns the daily bars of the last couple of weeks, the current session last
+func (s *StooqProvider) latest(ctx context.Context, symbol string) ([]stooqBar, error) {
+	end := time.Now()
+	bars, err := s.fetch(ctx, symbol, "1d", end.Add(-stooqQuoteWindow), end)
+	if err != nil {
+		return nil, err
+	}
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no price data found for symbol: %s", symbol)
+	}
+	return bars, nil
+}
+
+// fetch downloads and parses the CSV of a symbol, keeping the bars between start and end in ascending order
+func (s *StooqProvider) fetch(ctx context.Context, symbol, interval string, start, end time.Time) ([]stooqBar, error) {
+	stooqInterval, ok := map[string]string{"": "d", "1d": "d", "5d": "w", "1wk": "w", "1mo": "m", "3mo": "q"}[interval]
+	if !ok {
+		return nil, fmt.Errorf("stooq %s interval: %w", interval, ErrUnsupported)
+	}
+
+	endpoint := strings.NewReplacer(
+		"{symbol}", url.QueryEscape(StooqSymbol(symbol)),
+		"{interval}", stooqInterval,
+		"{start}", start.Format("20060102"),
+		"{end}", end.Format("20060102"),
+	).Replace(s.url)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("stooq request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &UnknownSymbolError{Symbol: symbol}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("stooq returned %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	bars, err := parseStooqCSV(string(body))
+	if err != nil {
+		return nil, err
+	}
+	if bars == nil {
+		return nil, &UnknownSymbolError{Symbol: symbol}
+	}
+
+	startDate, endDate := NormalizeDate(start), NormalizeDate(end)
+	filtered := bars[:0]
+	for _, bar := range bars {
+		if !bar.date.Before(startDate) && !bar.date.After(endDate) {
+			filtered = append(filtered, bar)
+		}
+	}
+	return filtered, nil
+}
+
+// parseStooqCSV parses a Date,Open,High,Low,Close[,Volume] CSV.
+// Stooq answers unknown symbols with a plain "No data" body, which returns nil bars.
+func parseStooqCSV(body string) ([]stooqBar, error) {
+	body = strings.TrimSpace(body)
+	if body == "" || strings.EqualFold(body, "No data") {
+		return nil, nil
+	}
+	if strings.HasPrefix(body, "Exceeded the daily hits limit") {
+		return nil, errors.New("stooq daily hits limit exceeded")
+	}
+
+	reader := csv.NewReader(strings.NewReader(body))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode stooq CSV: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "open", "high", "low", "close"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("stooq CSV is missing the %s column", name)
+		}
+	}
+
+	bars := make([]stooqBar, 0, len(records)-1)
+	for _, record := range records[1:] {
+		if len(record) < len(columns) {
+			continue
+		}
+		date, err := time.ParseInLocation("2006-01-02", record[columns["date"]], time.UTC)
+		if err != nil {
+			continue
+		}
+		value := func(name string) float64 {
+			v, _ := strconv.ParseFloat(record[columns[name]], 64)
+			return v
+		}
+
+		price := PriceData{Open: value("open"), High: value("high"), Low: value("low"), Close: value("close")}
+		// Stooq adjusts its history for splits and dividends, so the close is the adjusted close
+		price.AdjClose = price.Close
+		if i, ok := columns["volume"]; ok {
+			price.Volume, _ = strconv.ParseInt(strings.Split(record[i], ".")[0], 10, 64)
+		}
+		if !price.Valid() {
+			continue
+		}
+		bars = append(bars, stooqBar{date: date, price: price})
+	}
+	sort.Slice(bars, func(i, j int) bool { return bars[i].date.Before(bars[j].date) })
+	return bars, nil
+}
+
+// historyWindow returns the range a HistoryQuery covers, accepting both dates and unix timestamps
+func historyWindow(query HistoryQuery) (time.Time, time.Time, error) {
+	parse := func(value string) (time.Time, error) {
+		if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.Unix(unix, 0), nil
+		}
+		return time.ParseInLocation("2006-01-02", value, time.UTC)
+	}
+
+	end := time.Now()
+	if query.End != "" {
+		var err error
+		if end, err = parse(query.End); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid end %q: %w", query.End, err)
+		}
+	}
+	if query.Start != "" {
+		start, err := parse(query.Start)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid start %q: %w", query.Start, err)
+		}
+		return start, end, nil
+	}
+
+	chartRange := query.Range
+	if chartRange == "" {
+		chartRange = "1mo"
+	}
+	for suffix, add := range map[string]func(n int) time.Time{
+		"d":  func(n int) time.Time { return end.AddDate(0, 0, -n) },
+		"wk": func(n int) time.Time { return end.AddDate(0, 0, -7*n) },
+		"mo": func(n int) time.Time { return end.AddDate(0, -n, 0) },
+		"y":  func(n int) time.Time { return end.AddDate(-n, 0, 0) },
+	} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(chartRange, suffix)); err == nil && strings.HasSuffix(chartRange, suffix) {
+			return add(n), end, nil
+		}
+	}
+	switch chartRange {
+	case "ytd":
+		return time.Date(end.Year(), 1, 1, 0, 0, 0, 0, time.UTC), end, nil
+	case "max":
+		return time.Time{}, end, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unsupported range: %s", chartRange)
+}
@@ -0,0 +1,230 @@
+package yfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseStooqCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "daily bars",
+			body: "Date,Open,High,Low,Close,Volume\n2024-10-07,224.5,225.69,221.33,221.69,39505354\n2024-10-08,224.3,225.98,223.25,225.77,31855693\n",
+			want: []string{"2024-10-07", "2024-10-08"},
+		},
+		{
+			name: "unsorted rows",
+			body: "Date,Open,High,Low,Close,Volume\n2024-10-08,224.3,225.98,223.25,225.77,31855693\n2024-10-07,224.5,225.69,221.33,221.69,39505354\n",
+			want: []string{"2024-10-07", "2024-10-08"},
+		},
+		{
+			name: "invalid rows skipped",
+			body: "Date,Open,High,Low,Close,Volume\nyesterday,1,1,1,1,1\n2024-10-07,224.5,225.69,221.33,221.69,39505354\n2024-10-08,0,0,0,0,0\n2024-10-09,1,2\n",
+			want: []string{"2024-10-07"},
+		},
+		{
+			name: "without volume",
+			body: "Date,Open,High,Low,Close\n2024-10-07,224.5,225.69,221.33,221.69\n",
+			want: []string{"2024-10-07"},
+		},
+		{name: "no data", body: "No data", want: nil},
+		{name: "empty body", body: "\n", want: nil},
+		{name: "hits limit", body: "Exceeded the daily hits limit", wantErr: "daily hits limit"},
+		{name: "missing column", body: "Date,Open,High,Close\n2024-10-07,224.5,225.69,221.69\n", wantErr: "missing the low column"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bars, err := parseStooqCSV(tt.body)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseStooqCSV: %v", err)
+			}
+			if tt.want == nil && bars != nil {
+				t.Fatalf("bars = %+v, want nil", bars)
+			}
+
+			var dates []string
+			for _, bar := range bars {
+				dates = append(dates, bar.date.Format("2006-01-02"))
+			}
+			if strings.Join(dates, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("dates = %v, want %v", dates, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStooqCSVPrices(t *testing.T) {
+	bars, err := parseStooqCSV("Date,Open,High,Low,Close,Volume\n2024-10-07,224.5,225.69,221.33,221.69,39505354.5\n")
+	if err != nil {
+		t.Fatalf("parseStooqCSV: %v", err)
+	}
+	want := PriceData{Open: 224.5, High: 225.69, Low: 221.33, Close: 221.69, AdjClose: 221.69, Volume: 39505354}
+	if len(bars) != 1 || bars[0].price != want {
+		t.Errorf("bars = %+v, want %+v", bars, want)
+	}
+}
+
+func TestHistoryWindow(t *testing.T) {
+	end := time.Date(2024, 10, 18, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		query     HistoryQuery
+		wantStart time.Time
+	}{
+		{name: "start date", query: HistoryQuery{Start: "2024-01-02"}, wantStart: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "start unix", query: HistoryQuery{Start: "1704153600"}, wantStart: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{name: "days", query: HistoryQuery{Range: "5d"}, wantStart: time.Date(2024, 10, 13, 0, 0, 0, 0, time.UTC)},
+		{name: "weeks", query: HistoryQuery{Range: "2wk"}, wantStart: time.Date(2024, 10, 4, 0, 0, 0, 0, time.UTC)},
+		{name: "months", query: HistoryQuery{Range: "6mo"}, wantStart: time.Date(2024, 4, 18, 0, 0, 0, 0, time.UTC)},
+		{name: "years", query: HistoryQuery{Range: "2y"}, wantStart: time.Date(2022, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{name: "year to date", query: HistoryQuery{Range: "ytd"}, wantStart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "max", query: HistoryQuery{Range: "max"}, wantStart: time.Time{}},
+		{name: "default month", query: HistoryQuery{}, wantStart: time.Date(2024, 9, 18, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.End = "2024-10-18"
+			start, gotEnd, err := historyWindow(tt.query)
+			if err != nil {
+				t.Fatalf("historyWindow: %v", err)
+			}
+			if !start.Equal(tt.wantStart) || !gotEnd.Equal(end) {
+				t.Errorf("window = %s to %s, want %s to %s", start, gotEnd, tt.wantStart, end)
+			}
+		})
+	}
+
+	for _, query := range []HistoryQuery{{Range: "1h"}, {Range: "wk"}, {Start: "yesterday"}, {End: "tomorrow"}} {
+		if _, _, err := historyWindow(query); err == nil {
+			t.Errorf("historyWindow(%+v): want an error", query)
+		}
+	}
+}
+
+// serveStooq answers the CSV of a symbol with daily bars up to today, nope.us with "No data",
+// missing.us with a 404 and busy.us with the hits limit body
+func serveStooq(t *testing.T) (*StooqProvider, *[]string) {
+	t.Helper()
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.String())
+		switch symbol := strings.TrimSuffix(path.Base(r.URL.Path), ".csv"); symbol {
+		case "nope.us":
+			w.Write([]byte("No data"))
+		case "missing.us":
+			http.NotFound(w, r)
+		case "busy.us":
+			w.Write([]byte("Exceeded the daily hits limit"))
+		default:
+			today := NormalizeDate(time.Now())
+			body := "Date,Open,High,Low,Close,Volume\n"
+			for days := 40; days >= 0; days-- {
+				price := 100 + float64(40-days)
+				body += fmt.Sprintf("%s,%.2f,%.2f,%.2f,%.2f,1000\n", today.AddDate(0, 0, -days).Format("2006-01-02"), price, price+1, price-1, price)
+			}
+			w.Write([]byte(body))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return NewStooqProvider(srv.URL + "/{symbol}.csv?i={interval}&d1={start}&d2={end}"), &requests
+}
+
+func TestStooqProviderHistory(t *testing.T) {
+	provider, requests := serveStooq(t)
+	ctx := context.Background()
+
+	start := NormalizeDate(time.Now()).AddDate(0, 0, -5)
+	history, err := provider.History(ctx, "AAPL", HistoryQuery{Start: start.Format("2006-01-02"), Interval: "1d"})
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 6 {
+		t.Errorf("history has %d bars, want the 6 since %s", len(history), start.Format("2006-01-02"))
+	}
+	want := fmt.Sprintf("/aapl.us.csv?i=d&d1=%s&d2=%s", start.Format("20060102"), time.Now().Format("20060102"))
+	if len(*requests) != 1 || (*requests)[0] != want {
+		t.Errorf("requests = %v, want %s", *requests, want)
+	}
+
+	if _, err := provider.History(ctx, "AAPL", HistoryQuery{Range: "1d", Interval: "1h"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("History with an hourly interval err = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestStooqProviderErrors(t *testing.T) {
+	provider, _ := serveStooq(t)
+
+	tests := []struct {
+		symbol string
+		want   error
+	}{
+		{symbol: "NOPE", want: ErrUnknownSymbol},
+		{symbol: "MISSING", want: ErrUnknownSymbol},
+		{symbol: "BUSY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			_, err := provider.Quote(context.Background(), tt.symbol)
+			if err == nil {
+				t.Fatal("Quote: want an error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && !isOutage(err) {
+				t.Errorf("err = %v, want an outage the failover moves past", err)
+			}
+		})
+	}
+}
+
+func TestStooqProviderInfo(t *testing.T) {
+	provider, _ := serveStooq(t)
+	ctx := context.Background()
+
+	info, err := provider.Info(ctx, "AAPL")
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if info.Symbol != "AAPL" || info.Currency != "USD" || info.CurrencySymbol != "$" {
+		t.Errorf("info = %s in %s (%s), want AAPL in USD ($)", info.Symbol, info.Currency, info.CurrencySymbol)
+	}
+	if got := info.RegularMarketPrice; got == nil || got.Raw != 140 {
+		t.Errorf("RegularMarketPrice = %+v, want the close of today", got)
+	}
+
+	if _, err := provider.Info(ctx, "SAP.DE"); err != nil {
+		t.Errorf("Info(SAP.DE): %v", err)
+	}
+	if _, err := provider.Info(ctx, "SHEL.L"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Info(SHEL.L) err = %v, want ErrUnsupported", err)
+	}
+
+	infos, err := provider.Quotes(ctx, []string{"AAPL", "SHEL.L", "NOPE"})
+	if err != nil {
+		t.Fatalf("Quotes: %v", err)
+	}
+	if _, ok := infos["AAPL"]; len(infos) != 1 || !ok {
+		t.Errorf("Quotes = %v, want only AAPL", infos)
+	}
+}
@@ -0,0 +1,46 @@
+package yfa
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// maxSymbolSuggestions is the number of search matches suggested for an unknown symbol
+const maxSymbolSuggestions = 3
+
+// NormalizeSymbol returns the canonical form symbols are stored in
+func NormalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// ValidateSymbol checks that Yahoo has a quote for the symbol.
+// For an unknown symbol it returns an UnknownSymbolError carrying the closest search matches,
+// other errors (like throttling) are returned as is so they aren't mistaken for a typo.
+func ValidateSymbol(ctx context.Context, provider MarketDataProvider, symbol string) error {
+	symbol = NormalizeSymbol(symbol)
+	if symbol == "" {
+		return &UnknownSymbolError{Symbol: symbol}
+	}
+
+	info, err := provider.Info(ctx, symbol)
+	if err == nil && info.RegularMarketPrice != nil {
+		return nil
+	}
+	if err != nil && !errors.Is(err, ErrUnknownSymbol) {
+		return err
+	}
+
+	unknown := &UnknownSymbolError{Symbol: symbol}
+	if results, err := provider.Search(ctx, symbol); err == nil {
+		for _, result := range results {
+			if len(unknown.Suggestions) == maxSymbolSuggestions {
+				break
+			}
+			if result.Symbol != symbol {
+				unknown.Suggestions = append(unknown.Suggestions, result.Symbol)
+			}
+		}
+	}
+	return unknown
+}
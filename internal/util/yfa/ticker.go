@@ -1,6 +1,7 @@
 package yfa
 
 import (
+	"context"
 	"fmt"
 	"sort"
 )
@@ -37,8 +38,8 @@ func NewTickers(symbol []string) (result []*Ticker) {
 // This is a convenience wrapper around the History function. It fetches the historical
 // price data for the symbol, sorts the dates, and returns the most recent entry.
 // If you need more control or access to the full historical data, use the History method directly.
-func (t *Ticker) Quote() (PriceData, error) {
-	history, err := t.history.GetHistory(t.Symbol)
+func (t *Ticker) Quote(ctx context.Context) (PriceData, error) {
+	history, err := t.history.GetHistory(ctx, t.Symbol)
 	if err != nil {
 		return PriceData{}, err
 	}
@@ -50,6 +51,9 @@ func (t *Ticker) Quote() (PriceData, error) {
 	}
 	sort.Strings(dates)
 
+	if len(dates) == 0 {
+		return PriceData{}, fmt.Errorf("no price data found for symbol: %s", t.Symbol)
+	}
 	latestDate := dates[len(dates)-1]
 	latestPriceData := transformedData[latestDate]
 	return latestPriceData, nil
@@ -58,8 +62,8 @@ func (t *Ticker) Quote() (PriceData, error) {
 // Info retrieves the ticker information for the Ticker's symbol.
 // It returns a YahooTickerInfo struct containing metadata such as the symbol, name, currency, and market state.
 // If no information is found, it returns an error.
-func (t *Ticker) Info() (YahooTickerInfo, error) {
-	info, err := t.information.GetInfo(t.Symbol)
+func (t *Ticker) Info(ctx context.Context) (YahooTickerInfo, error) {
+	info, err := t.information.GetInfo(ctx, t.Symbol)
 	if err != nil {
 		return YahooTickerInfo{}, err
 	}
@@ -70,37 +74,59 @@ func (t *Ticker) Info() (YahooTickerInfo, error) {
 	return info, nil
 }
 
+// Fundamentals retrieves the valuation, dividend, earnings and profile data for the Ticker's symbol.
+func (t *Ticker) Fundamentals(ctx context.Context) (Fundamentals, error) {
+	return t.information.GetFundamentals(ctx, t.Symbol)
+}
+
 // History retrieves the historical price data for the Ticker's symbol based on the provided query.
 // It returns a map of date strings to PriceData structs.
 // The query can specify the range, interval, and other parameters for the historical data.
-func (t *Ticker) History(query HistoryQuery) (map[string]PriceData, error) {
+func (t *Ticker) History(ctx context.Context, query HistoryQuery) (map[string]PriceData, error) {
 	t.history.SetQuery(query)
-	history, err := t.history.GetHistory(t.Symbol)
+	history, err := t.history.GetHistory(ctx, t.Symbol)
 	if err != nil {
 		return nil, err
 	}
 	return t.history.transformData(history), nil
 }
 
-// OptionChain retrieves the option chain for the Ticker's symbol.
-// It returns an OptionData struct containing the options available for the ticker.
-// If no options are found, it returns an empty OptionData struct.
-func (t *Ticker) OptionChain() OptionData {
-	optionChain := t.option.GetOptionChain(t.Symbol)
+// Chart retrieves the historical price data and the dividends and splits for the Ticker's symbol from a single chart request.
+// The corporate actions are sorted by date.
+func (t *Ticker) Chart(ctx context.Context, query HistoryQuery) (ChartData, error) {
+	t.history.SetQuery(query)
+	history, err := t.history.GetHistory(ctx, t.Symbol)
+	if err != nil {
+		return ChartData{}, err
+	}
+	return ChartData{
+		History: t.history.transformData(history),
+		Actions: t.history.transformActions(history),
+	}, nil
+}
+
+// OptionChain retrieves the nearest option chain for the Ticker's symbol.
+// It returns an UnknownSymbolError when Yahoo doesn't know the symbol and ErrNoOptions when it has no options listed.
+func (t *Ticker) OptionChain(ctx context.Context) (OptionData, error) {
+	optionChain, err := t.option.GetOptionChain(ctx, t.Symbol)
+	if err != nil {
+		return OptionData{}, err
+	}
 	return t.option.transformData(optionChain)
 }
 
 // OptionChainByExpiration retrieves the option chain for the Ticker's symbol filtered by a specific expiration date.
-// It returns an OptionData struct containing the options available for the ticker on that expiration date.
-// If no options are found for the specified expiration, it returns an empty OptionData struct.
-func (t *Ticker) OptionChainByExpiration(expiration string) OptionData {
-	optionChain := t.option.GetOptionChainByExpiration(t.Symbol, expiration)
+// It returns ErrNoOptions when no options expire on that date.
+func (t *Ticker) OptionChainByExpiration(ctx context.Context, expiration string) (OptionData, error) {
+	optionChain, err := t.option.GetOptionChainByExpiration(ctx, t.Symbol, expiration)
+	if err != nil {
+		return OptionData{}, err
+	}
 	return t.option.transformData(optionChain)
 }
 
 // ExpirationDates retrieves a list of available expiration dates for options on the Ticker's symbol.
-// It returns a slice of strings representing the expiration dates.
-func (t *Ticker) ExpirationDates() []string {
-	expirationDates := t.option.GetExpirationDates(t.Symbol)
-	return expirationDates
+// It returns a slice of strings representing the expiration dates, or ErrNoOptions when there are none.
+func (t *Ticker) ExpirationDates(ctx context.Context) ([]string, error) {
+	return t.option.GetExpirationDates(ctx, t.Symbol)
 }
@@ -0,0 +1,69 @@
+package yfa
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// chartWithEvents is a two day chart response with a dividend and a 4:1 split
+const chartWithEvents = `{"chart":{"result":[{
+	"meta":{"currency":"USD","symbol":"AAPL","exchangeTimezoneName":"America/New_York","gmtoffset":-14400},
+	"timestamp":[1724851800,1724938200],
+	"events":{
+		"dividends":{"1724851800":{"amount":0.25,"date":1724851800}},
+		"splits":{"1724938200":{"date":1724938200,"numerator":4,"denominator":1,"splitRatio":"4:1"}}
+	},
+	"indicators":{
+		"quote":[{"open":[226.0,228.0],"high":[227.0,230.0],"low":[225.0,227.5],"close":[226.5,229.0],"volume":[1000,2000]}],
+		"adjclose":[{"adjclose":[226.0,229.0]}]
+	}
+}],"error":null}}`
+
+func TestTickerChartSingleRequest(t *testing.T) {
+	var requests atomic.Int32
+	serveYahoo(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v8/finance/chart/") {
+			http.NotFound(w, r)
+			return
+		}
+		requests.Add(1)
+		if got := r.URL.Query().Get("events"); got != "div,splits" {
+			t.Errorf("events = %q, want div,splits", got)
+		}
+		w.Write([]byte(chartWithEvents))
+	})
+
+	chart, err := NewTicker("AAPL").Chart(context.Background(), HistoryQuery{Start: "2024-08-28", Interval: "1d"})
+	if err != nil {
+		t.Fatalf("Chart: %v", err)
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("chart requests = %d, want 1", got)
+	}
+
+	if len(chart.History) != 2 {
+		t.Fatalf("history has %d bars, want 2", len(chart.History))
+	}
+	if bar := chart.History["2024-08-29"]; bar.Close != 229 || bar.Volume != 2000 {
+		t.Errorf("2024-08-29 = %+v, want close 229 and volume 2000", bar)
+	}
+
+	want := []CorporateAction{
+		{Date: "2024-08-28", Type: ActionDividend, Amount: 0.25},
+		{Date: "2024-08-29", Type: ActionSplit, Numerator: 4, Denominator: 1},
+	}
+	if len(chart.Actions) != len(want) {
+		t.Fatalf("actions = %+v, want %+v", chart.Actions, want)
+	}
+	for i := range want {
+		if chart.Actions[i] != want[i] {
+			t.Errorf("action %d = %+v, want %+v", i, chart.Actions[i], want[i])
+		}
+	}
+	if ratio := chart.Actions[1].Ratio(); ratio != 4 {
+		t.Errorf("split ratio = %v, want 4", ratio)
+	}
+}